@@ -3,11 +3,19 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
+// DevelopmentEnv is the APP_ENV of local setups, where conveniences such as
+// writing emails to files are allowed by default.
+const DevelopmentEnv = "development"
+
 type Config struct {
+	// AppEnv is the deployment environment, production unless set.
+	AppEnv     string
 	DBHost     string
 	DBUser     string
 	DBPassword string
@@ -15,6 +23,46 @@ type Config struct {
 	DBPort     string
 	ServerPort string
 	JwtSecret  string
+	// MaxPageSize caps the pageSize accepted by the listing endpoints.
+	MaxPageSize int
+	// TrashRetentionDays is how long deleted products stay in the trash.
+	TrashRetentionDays int
+	// AccessTokenTTLMinutes is the lifetime of the JWT access tokens and
+	// RefreshTokenTTLDays the lifetime of a session.
+	AccessTokenTTLMinutes int
+	RefreshTokenTTLDays   int
+	// PasswordResetTTLMinutes and EmailVerificationTTLHours are the lifetimes
+	// of the links sent by email.
+	PasswordResetTTLMinutes   int
+	EmailVerificationTTLHours int
+	// RequireEmailVerification refuses the sign-in of unverified users.
+	RequireEmailVerification bool
+	// AppURL is the web app the emailed links point to.
+	AppURL string
+	// PasswordPolicy is enforced on sign-up and password reset.
+	PasswordPolicy PasswordPolicy
+	// LoginMaxFailures and LoginIPMaxFailures are the failed sign-ins that
+	// lock an account or an address for LoginLockoutMinutes. Before that,
+	// each failure doubles the wait, starting at LoginBackoffBaseSeconds.
+	LoginMaxFailures        int
+	LoginIPMaxFailures      int
+	LoginLockoutMinutes     int
+	LoginBackoffBaseSeconds int
+	// LoginAttemptStore is where failures are kept: postgres or memory.
+	LoginAttemptStore string
+	// TrustedProxies (CIDRs) or TrustedProxyCount (hops) describe the reverse
+	// proxies in front of the API, so the client address can be read from
+	// X-Forwarded-For. With neither, the connection address is used.
+	TrustedProxies    []string
+	TrustedProxyCount int
+}
+
+type PasswordPolicy struct {
+	MinLength        int
+	RequireLetter    bool
+	RequireUppercase bool
+	RequireDigit     bool
+	RequireSymbol    bool
 }
 
 type CloudinaryConfig struct {
@@ -30,6 +78,27 @@ type S3Config struct {
 	SecretKey  string
 }
 
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+type MailerConfig struct {
+	Provider string
+	Dir      string
+	SMTP     SMTPConfig
+}
+
+type NotifierConfig struct {
+	Provider   string
+	WebhookURL string
+	Recipients []string
+	SMTP       SMTPConfig
+}
+
 func LoadConfig() *Config {
 	err := godotenv.Load()
 	if err != nil {
@@ -37,13 +106,30 @@ func LoadConfig() *Config {
 	}
 
 	config := &Config{
-		DBHost:     getEnv("DB_HOST", "postgres"),
-		DBUser:     getEnv("DB_USER", "postgres"),
-		DBPassword: getEnv("DB_PASSWORD", "secret"),
-		DBName:     getEnv("DB_NAME", "stock_db"),
-		DBPort:     getEnv("DB_PORT", "5432"),
-		ServerPort: getEnv("SERVER_PORT", "8080"),
-		JwtSecret:  getEnv("JWT_SECRET", "12345"),
+		AppEnv:                    getEnv("APP_ENV", "production"),
+		DBHost:                    getEnv("DB_HOST", "postgres"),
+		DBUser:                    getEnv("DB_USER", "postgres"),
+		DBPassword:                getEnv("DB_PASSWORD", "secret"),
+		DBName:                    getEnv("DB_NAME", "stock_db"),
+		DBPort:                    getEnv("DB_PORT", "5432"),
+		ServerPort:                getEnv("SERVER_PORT", "8080"),
+		JwtSecret:                 getEnv("JWT_SECRET", "12345"),
+		MaxPageSize:               getEnvInt("MAX_PAGE_SIZE", 100),
+		TrashRetentionDays:        getEnvInt("TRASH_RETENTION_DAYS", 30),
+		AccessTokenTTLMinutes:     getEnvInt("ACCESS_TOKEN_TTL_MINUTES", 15),
+		RefreshTokenTTLDays:       getEnvInt("REFRESH_TOKEN_TTL_DAYS", 30),
+		PasswordResetTTLMinutes:   getEnvInt("PASSWORD_RESET_TTL_MINUTES", 60),
+		EmailVerificationTTLHours: getEnvInt("EMAIL_VERIFICATION_TTL_HOURS", 48),
+		RequireEmailVerification:  getEnvBool("REQUIRE_EMAIL_VERIFICATION", false),
+		AppURL:                    strings.TrimSuffix(getEnv("APP_URL", "http://localhost:3000"), "/"),
+		PasswordPolicy:            LoadPasswordPolicy(),
+		LoginMaxFailures:          getEnvInt("LOGIN_MAX_FAILURES", 5),
+		LoginIPMaxFailures:        getEnvInt("LOGIN_IP_MAX_FAILURES", 20),
+		LoginLockoutMinutes:       getEnvInt("LOGIN_LOCKOUT_MINUTES", 15),
+		LoginBackoffBaseSeconds:   getEnvInt("LOGIN_BACKOFF_BASE_SECONDS", 1),
+		LoginAttemptStore:         getEnv("LOGIN_ATTEMPT_STORE", "postgres"),
+		TrustedProxies:            getEnvList("TRUSTED_PROXIES"),
+		TrustedProxyCount:         getEnvInt("TRUSTED_PROXY_COUNT", 0),
 	}
 
 	return config
@@ -66,6 +152,43 @@ func LoadS3Config() S3Config {
 	}
 }
 
+func LoadSMTPConfig() SMTPConfig {
+	return SMTPConfig{
+		Host:     getEnv("SMTP_HOST", "localhost"),
+		Port:     getEnv("SMTP_PORT", "1025"),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     getEnv("SMTP_FROM", "no-reply@savina.local"),
+	}
+}
+
+func LoadPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:        getEnvInt("PASSWORD_MIN_LENGTH", 8),
+		RequireLetter:    getEnvBool("PASSWORD_REQUIRE_LETTER", true),
+		RequireUppercase: getEnvBool("PASSWORD_REQUIRE_UPPERCASE", false),
+		RequireDigit:     getEnvBool("PASSWORD_REQUIRE_DIGIT", true),
+		RequireSymbol:    getEnvBool("PASSWORD_REQUIRE_SYMBOL", false),
+	}
+}
+
+func LoadMailerConfig() MailerConfig {
+	return MailerConfig{
+		Provider: os.Getenv("MAILER_PROVIDER"),
+		Dir:      getEnv("MAILER_DIR", "tmp/mail"),
+		SMTP:     LoadSMTPConfig(),
+	}
+}
+
+func LoadNotifierConfig() NotifierConfig {
+	return NotifierConfig{
+		Provider:   getEnv("NOTIFIER_PROVIDER", "log"),
+		WebhookURL: os.Getenv("NOTIFIER_WEBHOOK_URL"),
+		Recipients: getEnvList("NOTIFIER_RECIPIENTS"),
+		SMTP:       LoadSMTPConfig(),
+	}
+}
+
 func getEnv(key, fallback string) string {
 	value, exists := os.LookupEnv(key)
 
@@ -74,3 +197,30 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+func getEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(getEnv(key, ""))
+	if err != nil || value < 1 {
+		return fallback
+	}
+	return value
+}
+
+// getEnvList splits a comma separated variable, dropping empty entries.
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	value, err := strconv.ParseBool(getEnv(key, ""))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"net/netip"
 	"os"
 	"time"
 
@@ -14,11 +15,23 @@ import (
 	"github.com/reinaldo-silva/savina-stock/internal/domain/auth"
 	"github.com/reinaldo-silva/savina-stock/internal/domain/category"
 	"github.com/reinaldo-silva/savina-stock/internal/domain/image_service"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/inventory_count"
 	"github.com/reinaldo-silva/savina-stock/internal/domain/product"
 	"github.com/reinaldo-silva/savina-stock/internal/domain/product_image"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/product_variant"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/sale"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/stock_alert"
 	"github.com/reinaldo-silva/savina-stock/internal/domain/user"
 	"github.com/reinaldo-silva/savina-stock/internal/infrastructure/db/gorm"
 	s3_provider "github.com/reinaldo-silva/savina-stock/internal/infrastructure/image_provider/aws"
+	memory_login_attempt "github.com/reinaldo-silva/savina-stock/internal/infrastructure/login_attempt/memory"
+	"github.com/reinaldo-silva/savina-stock/internal/infrastructure/mailer"
+	file_mailer "github.com/reinaldo-silva/savina-stock/internal/infrastructure/mailer/file"
+	smtp_mailer "github.com/reinaldo-silva/savina-stock/internal/infrastructure/mailer/smtp"
+	"github.com/reinaldo-silva/savina-stock/internal/infrastructure/notifier"
+	log_notifier "github.com/reinaldo-silva/savina-stock/internal/infrastructure/notifier/log"
+	smtp_notifier "github.com/reinaldo-silva/savina-stock/internal/infrastructure/notifier/smtp"
+	webhook_notifier "github.com/reinaldo-silva/savina-stock/internal/infrastructure/notifier/webhook"
 	jwt_middleware "github.com/reinaldo-silva/savina-stock/internal/middleware/jwt"
 )
 
@@ -38,6 +51,56 @@ func limitRequestBodySize(maxBytes int64) func(http.Handler) http.Handler {
 	}
 }
 
+func newNotifier(cfg config.NotifierConfig) (notifier.Implementation, error) {
+	switch cfg.Provider {
+	case "webhook":
+		return webhook_notifier.NewWebhookNotifier(cfg.WebhookURL)
+	case "smtp":
+		return smtp_notifier.NewSMTPNotifier(cfg.SMTP, cfg.Recipients)
+	case "log", "":
+		return log_notifier.NewLogNotifier(), nil
+	}
+	return nil, fmt.Errorf("unknown notifier provider: %s", cfg.Provider)
+}
+
+// clientIPMiddleware stores the client address read with middleware.GetClientIP.
+// X-Forwarded-For is only read behind configured proxies, and only the
+// entries they appended are trusted, so clients cannot pick their address.
+func clientIPMiddleware(cfg *config.Config) (func(http.Handler) http.Handler, error) {
+	if len(cfg.TrustedProxies) > 0 {
+		for _, prefix := range cfg.TrustedProxies {
+			if _, err := netip.ParsePrefix(prefix); err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %v", prefix, err)
+			}
+		}
+		return middleware.ClientIPFromXFF(cfg.TrustedProxies...), nil
+	}
+
+	if cfg.TrustedProxyCount > 0 {
+		return middleware.ClientIPFromXFFTrustedProxies(cfg.TrustedProxyCount), nil
+	}
+
+	return middleware.ClientIPFromRemoteAddr, nil
+}
+
+// newMailer only falls back to the file mailer in development, so a
+// deployment that forgot MAILER_PROVIDER fails to start instead of writing
+// password reset links to disk.
+func newMailer(appEnv string, cfg config.MailerConfig) (mailer.Implementation, error) {
+	switch cfg.Provider {
+	case "smtp":
+		return smtp_mailer.NewSMTPMailer(cfg.SMTP)
+	case "file":
+		return file_mailer.NewFileMailer(cfg.Dir)
+	case "":
+		if appEnv == config.DevelopmentEnv {
+			return file_mailer.NewFileMailer(cfg.Dir)
+		}
+		return nil, fmt.Errorf("MAILER_PROVIDER must be set when APP_ENV is %s", appEnv)
+	}
+	return nil, fmt.Errorf("unknown mailer provider: %s", cfg.Provider)
+}
+
 func (a *App) Initialize(cfg *config.Config) {
 
 	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=require",
@@ -46,12 +109,34 @@ func (a *App) Initialize(cfg *config.Config) {
 	connection := gorm.NewGormDB(dsn)
 
 	s3Config := config.LoadS3Config()
+	notifierConfig := config.LoadNotifierConfig()
+	mailerConfig := config.LoadMailerConfig()
 
 	s3Provider, err := s3_provider.NewS3Provider(s3Config)
 	if err != nil {
 		log.Fatal("failed to initialize cloudinary service: ", err)
 	}
 
+	stockNotifier, err := newNotifier(notifierConfig)
+	if err != nil {
+		log.Fatal("failed to initialize notifier: ", err)
+	}
+
+	userMailer, err := newMailer(cfg.AppEnv, mailerConfig)
+	if err != nil {
+		log.Fatal("failed to initialize mailer: ", err)
+	}
+
+	var loginAttemptStore user.LoginAttemptStore
+	switch cfg.LoginAttemptStore {
+	case "postgres", "":
+		loginAttemptStore = gorm.NewGormLoginAttemptStore(connection)
+	case "memory":
+		loginAttemptStore = memory_login_attempt.NewMemoryLoginAttemptStore()
+	default:
+		log.Fatal("unknown login attempt store: ", cfg.LoginAttemptStore)
+	}
+
 	a.Router = chi.NewRouter()
 
 	a.Router.Use(limitRequestBodySize(10 << 20)) // 10MB
@@ -65,8 +150,6 @@ func (a *App) Initialize(cfg *config.Config) {
 		allowedOrigins = []string{"http://localhost:3000"}
 	}
 
-	jwtMiddleware := jwt_middleware.NewJwtMiddleware([]byte(cfg.JwtSecret))
-
 	a.Router.Use(cors.Handler(cors.Options{
 		AllowedOrigins: allowedOrigins,
 		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
@@ -74,49 +157,107 @@ func (a *App) Initialize(cfg *config.Config) {
 		ExposedHeaders: []string{"Link"},
 		MaxAge:         300,
 	}))
+	clientIP, err := clientIPMiddleware(cfg)
+	if err != nil {
+		log.Fatal("failed to configure trusted proxies: ", err)
+	}
+	a.Router.Use(clientIP)
 	a.Router.Use(middleware.Logger)
 	a.Router.Use(middleware.Recoverer)
 
 	userRepo := gorm.NewGormUserRepository(connection)
+	sessionRepo := gorm.NewGormSessionRepository(connection)
+	userTokenRepo := gorm.NewGormUserTokenRepository(connection)
 	productRepo := gorm.NewGormProductRepository(connection)
 	categoryRepo := gorm.NewCategoryRepository(connection)
 	imageRepo := gorm.NewGormImageRepository(connection)
+	saleRepo := gorm.NewGormSaleRepository(connection)
+	movementRepo := gorm.NewGormStockMovementRepository(connection)
+	auditRepo := gorm.NewGormProductAuditRepository(connection)
+	inventoryCountRepo := gorm.NewGormInventoryCountRepository(connection)
+	variantRepo := gorm.NewGormVariantRepository(connection)
 
 	imageService := image_service.NewImageService(s3Provider)
+	alertService := stock_alert.NewStockAlertService(stockNotifier)
 
-	userUseCase := user.NewUserUseCase(userRepo)
-	productUseCase := product.NewProductUseCase(productRepo, categoryRepo, imageRepo, imageService)
-	categoryUseCase := category.NewCategoryUseCase(categoryRepo)
+	userUseCase := user.NewUserUseCase(userRepo, sessionRepo, userTokenRepo, loginAttemptStore, userMailer)
+	productUseCase := product.NewProductUseCase(productRepo, categoryRepo, imageRepo, movementRepo, auditRepo, imageService, alertService)
+	categoryUseCase := category.NewCategoryUseCase(categoryRepo, imageService)
 	imageUseCase := product_image.NewImageUseCase(imageService, imageRepo)
+	saleUseCase := sale.NewSaleUseCase(saleRepo, alertService)
+	inventoryCountUseCase := inventory_count.NewInventoryCountUseCase(inventoryCountRepo, productRepo)
+	variantUseCase := product_variant.NewVariantUseCase(variantRepo, alertService)
+
+	jwtMiddleware := jwt_middleware.NewJwtMiddleware([]byte(cfg.JwtSecret), userUseCase)
+
+	startScheduler(append(
+		productJobs(productUseCase, time.Duration(cfg.TrashRetentionDays)*24*time.Hour),
+		userJobs(userUseCase)...)...)
 
 	authHandler := auth.NewAuthHandler(userUseCase)
-	userHandler := user.NewUserHandler(userUseCase)
-	productHandler := product.NewProductHandler(productUseCase, imageService)
+	userHandler := user.NewUserHandler(userUseCase, cfg.MaxPageSize)
+	productHandler := product.NewProductHandler(productUseCase, imageService, cfg.MaxPageSize)
 	categoryHandler := category.NewCategoryHandler(categoryUseCase)
 	imageHandler := product_image.NewImageHandler(imageUseCase)
+	saleHandler := sale.NewSaleHandler(saleUseCase, cfg.MaxPageSize)
+	inventoryCountHandler := inventory_count.NewInventoryCountHandler(inventoryCountUseCase)
+	variantHandler := product_variant.NewVariantHandler(variantUseCase)
 
 	a.Router.Route("/users", func(r chi.Router) {
 		r.Use(jwtMiddleware.ValidateToken)
 		r.Use(jwtMiddleware.RequireRoles(string(user.AdminRole)))
 		r.Get("/", userHandler.GetUsers)
 		r.Post("/", userHandler.CreateUser)
+		r.Get("/{id}", userHandler.GetUser)
+		r.Put("/{id}", userHandler.UpdateUser)
+		r.Delete("/{id}", userHandler.DeactivateUser)
+		r.Post("/{id}/activate", userHandler.ActivateUser)
+		r.Patch("/{id}/role", userHandler.ChangeUserRole)
+		r.Delete("/{id}/sessions", userHandler.RevokeSessions)
+		r.Post("/{id}/unlock", userHandler.UnlockUser)
+	})
+
+	a.Router.Route("/me", func(r chi.Router) {
+		r.Use(jwtMiddleware.ValidateToken)
+		r.Use(jwtMiddleware.RequireRoles(string(user.AdminRole), string(user.ClientRole)))
+		r.Get("/", userHandler.GetMe)
+		r.Put("/", userHandler.UpdateMe)
+		r.Post("/password", userHandler.ChangeMyPassword)
 	})
 
 	a.Router.Route("/auth", func(r chi.Router) {
 		r.Post("/sign-up", authHandler.SignUp)
 		r.Post("/sign-in", authHandler.SignIn)
+		r.Post("/refresh", authHandler.Refresh)
+		r.Post("/forgot-password", authHandler.ForgotPassword)
+		r.Post("/reset-password", authHandler.ResetPassword)
+		r.Post("/verify-email", authHandler.VerifyEmail)
+		r.Post("/verify-email/resend", authHandler.ResendVerification)
+		r.Group(func(r chi.Router) {
+			r.Use(jwtMiddleware.ValidateToken)
+			r.Post("/sign-out", authHandler.SignOut)
+			r.Post("/sign-out-all", authHandler.SignOutAll)
+		})
 	})
 
 	a.Router.Route("/products", func(r chi.Router) {
 		r.Get("/", productHandler.GetProducts)
 		r.Get("/{slug}", productHandler.GetProductBySlug)
 		r.Get("/{slug}/images", productHandler.GetProductImages)
+		r.Get("/{slug}/variants", variantHandler.GetVariants)
 		r.Group(func(r chi.Router) {
 			r.Use(jwtMiddleware.ValidateToken)
 			r.Use(jwtMiddleware.RequireRoles(string(user.AdminRole)))
 			r.Get("/to-admin", productHandler.GetProductsToAdmin)
 			r.Get("/to-admin/{slug}", productHandler.GetProductBySlugToAdmin)
+			r.Get("/low-stock", productHandler.GetLowStockProducts)
+			r.Get("/by-code/{code}", productHandler.GetProductByCode)
+			r.Get("/{slug}/barcode", productHandler.GetProductBarcode)
 			r.Post("/", productHandler.CreateProduct)
+			r.Post("/import", productHandler.ImportProducts)
+			r.Get("/export", productHandler.ExportProducts)
+			r.Get("/trash", productHandler.GetTrash)
+			r.Post("/{slug}/restore", productHandler.RestoreProduct)
 			r.Delete("/{slug}", productHandler.DeleteProduct)
 			r.Put("/{slug}", productHandler.UpdateProduct)
 			r.Patch("/{slug}/upload-image", productHandler.UploadImages)
@@ -125,22 +266,56 @@ func (a *App) Initialize(cfg *config.Config) {
 			r.Patch("/{slug}/available/switch", productHandler.SwitchAvailable)
 			r.Patch("/{slug}/stock-entry", productHandler.ProductStockEntry)
 			r.Patch("/{slug}/stock-out", productHandler.ProductStockOut)
+			r.Get("/{slug}/stock-movements", productHandler.GetStockMovements)
+			r.Get("/{slug}/audit", productHandler.GetProductAudit)
+			r.Post("/{slug}/variants", variantHandler.CreateVariant)
+			r.Put("/{slug}/variants/{variant_id}", variantHandler.UpdateVariant)
+			r.Delete("/{slug}/variants/{variant_id}", variantHandler.DeleteVariant)
+			r.Patch("/{slug}/variants/{variant_id}/available/switch", variantHandler.SwitchAvailable)
+			r.Patch("/{slug}/variants/{variant_id}/stock-entry", variantHandler.StockEntry)
+			r.Patch("/{slug}/variants/{variant_id}/stock-out", variantHandler.StockOut)
 		})
 
 	})
 
 	a.Router.Route("/category", func(r chi.Router) {
 		r.Get("/", categoryHandler.GetAllCategories)
-		r.Get("/{id}", categoryHandler.GetCategoryByID)
+		r.Get("/tree", categoryHandler.GetCategoryTree)
+		r.Get("/{slug}", categoryHandler.GetCategoryBySlug)
+		r.Get("/{slug}/image", categoryHandler.GetCategoryImage)
 		r.Group(func(r chi.Router) {
 			r.Use(jwtMiddleware.ValidateToken)
 			r.Use(jwtMiddleware.RequireRoles(string(user.AdminRole)))
 			r.Post("/", categoryHandler.CreateCategory)
 			r.Delete("/{id}", categoryHandler.DeleteCategory)
+			r.Put("/reorder", categoryHandler.ReorderCategories)
 			r.Put("/{id}", categoryHandler.UpdateCategory)
+			r.Patch("/{id}/upload-image", categoryHandler.UploadCategoryImage)
+			r.Delete("/{id}/image", categoryHandler.DeleteCategoryImage)
 		})
 	})
 
+	a.Router.Route("/sales", func(r chi.Router) {
+		r.Use(jwtMiddleware.ValidateToken)
+		r.Use(jwtMiddleware.RequireRoles(string(user.AdminRole)))
+		r.Get("/", saleHandler.GetSales)
+		r.Get("/{id}", saleHandler.GetSaleByID)
+		r.Post("/", saleHandler.CreateSale)
+		r.Patch("/{id}/cancel", saleHandler.CancelSale)
+	})
+
+	a.Router.Route("/inventory-counts", func(r chi.Router) {
+		r.Use(jwtMiddleware.ValidateToken)
+		r.Use(jwtMiddleware.RequireRoles(string(user.AdminRole)))
+		r.Get("/", inventoryCountHandler.GetInventoryCounts)
+		r.Post("/", inventoryCountHandler.OpenInventoryCount)
+		r.Get("/{id}", inventoryCountHandler.GetInventoryCountByID)
+		r.Put("/{id}/items", inventoryCountHandler.SubmitItems)
+		r.Get("/{id}/variance", inventoryCountHandler.GetVariance)
+		r.Post("/{id}/commit", inventoryCountHandler.CommitInventoryCount)
+		r.Patch("/{id}/cancel", inventoryCountHandler.CancelInventoryCount)
+	})
+
 	a.Router.Route("/image", func(r chi.Router) {
 		r.Get("/{uuid}", imageHandler.GetImage)
 		r.Group(func(r chi.Router) {
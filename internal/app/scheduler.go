@@ -0,0 +1,86 @@
+package app
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/reinaldo-silva/savina-stock/internal/domain/product"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/user"
+)
+
+// scheduledJob is a background task run once at startup and then every
+// Interval.
+type scheduledJob struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) (int, error)
+}
+
+const (
+	availabilityScheduleInterval = time.Minute
+	trashPurgeInterval           = time.Hour
+	tokenCleanupInterval         = 24 * time.Hour
+	loginAttemptCleanupInterval  = time.Hour
+)
+
+func productJobs(uc *product.ProductUseCase, trashRetention time.Duration) []scheduledJob {
+	return []scheduledJob{
+		{
+			Name:     "product availability schedule",
+			Interval: availabilityScheduleInterval,
+			Run:      uc.ApplyAvailabilitySchedule,
+		},
+		{
+			Name:     "product trash purge",
+			Interval: trashPurgeInterval,
+			Run: func(ctx context.Context) (int, error) {
+				return uc.PurgeTrash(ctx, trashRetention)
+			},
+		},
+	}
+}
+
+func userJobs(uc *user.UserUseCase) []scheduledJob {
+	return []scheduledJob{
+		{
+			Name:     "expired session cleanup",
+			Interval: tokenCleanupInterval,
+			Run:      uc.PurgeExpiredSessions,
+		},
+		{
+			Name:     "expired user token cleanup",
+			Interval: tokenCleanupInterval,
+			Run:      uc.PurgeExpiredUserTokens,
+		},
+		{
+			Name:     "stale login attempt cleanup",
+			Interval: loginAttemptCleanupInterval,
+			Run:      uc.PurgeStaleLoginAttempts,
+		},
+	}
+}
+
+// startScheduler runs each job in its own goroutine, logging how many items a
+// run changed and any failure.
+func startScheduler(jobs ...scheduledJob) {
+	for _, job := range jobs {
+		go runJob(job)
+	}
+}
+
+func runJob(job scheduledJob) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		changed, err := job.Run(context.Background())
+		if err != nil {
+			log.Printf("scheduled job %q failed: %v", job.Name, err)
+		} else if changed > 0 {
+			log.Printf("scheduled job %q changed %d item(s)", job.Name, changed)
+		}
+
+		<-ticker.C
+	}
+}
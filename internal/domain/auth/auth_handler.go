@@ -2,12 +2,16 @@ package auth
 
 import (
 	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/reinaldo-silva/savina-stock/internal/domain/user"
 	error_response "github.com/reinaldo-silva/savina-stock/package/response/error"
 	"github.com/reinaldo-silva/savina-stock/package/response/response"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/reinaldo-silva/savina-stock/utils"
 )
 
 type AuthHandler struct {
@@ -19,81 +23,256 @@ func NewAuthHandler(uc *user.UserUseCase) *AuthHandler {
 }
 
 func (h *AuthHandler) SignUp(w http.ResponseWriter, r *http.Request) {
-	var newUser user.User
+	var signUpData user.SignUpRequest
 
-	err := json.NewDecoder(r.Body).Decode(&newUser)
+	err := json.NewDecoder(r.Body).Decode(&signUpData)
 	if err != nil {
 		appError := error_response.NewAppError("Invalid input data", http.StatusBadRequest)
 		h.sendErrorResponse(w, appError)
 		return
 	}
 
-	if newUser.Name == "" || newUser.Email == "" || newUser.Password == "" {
-		appError := error_response.NewAppError("Name, email, and password are required", http.StatusBadRequest)
+	createdUser, err := h.useCase.SignUp(signUpData)
+	if err != nil {
+		var validation *user.ValidationError
+		if errors.As(err, &validation) {
+			appError := error_response.NewAppError("Invalid sign-up data", http.StatusBadRequest).WithErrors(validation.Errors)
+			h.sendErrorResponse(w, appError)
+			return
+		}
+		appError := error_response.NewAppError(err.Error(), http.StatusInternalServerError)
 		h.sendErrorResponse(w, appError)
 		return
 	}
 
-	userFound, _ := h.useCase.GetByEmail(newUser.Email)
+	appResponse := response.NewAppResponse(createdUser, "User created successfully", nil)
+	h.sendSuccessResponse(w, appResponse)
+}
+
+func (h *AuthHandler) SignIn(w http.ResponseWriter, r *http.Request) {
+	var loginData struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
 
-	if userFound != nil {
-		appError := error_response.NewAppError("Email ja está em uso!", http.StatusBadRequest)
+	err := json.NewDecoder(r.Body).Decode(&loginData)
+	if err != nil {
+		appError := error_response.NewAppError("Invalid input data", http.StatusBadRequest)
 		h.sendErrorResponse(w, appError)
 		return
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newUser.Password), bcrypt.DefaultCost)
+	if loginData.Email == "" || loginData.Password == "" {
+		appError := error_response.NewAppError("Email and password are required", http.StatusBadRequest)
+		h.sendErrorResponse(w, appError)
+		return
+	}
+
+	signedInUser, tokens, err := h.useCase.SignInUseCase(loginData.Email, loginData.Password, clientIP(r))
 	if err != nil {
-		appError := error_response.NewAppError("Error generating password hash", http.StatusInternalServerError)
+		var throttled *user.LoginThrottledError
+		if errors.As(err, &throttled) {
+			retryAfter := int(math.Ceil(throttled.RetryAfter.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			appError := error_response.NewAppError(err.Error(), http.StatusTooManyRequests)
+			h.sendErrorResponse(w, appError)
+			return
+		}
+		status := http.StatusUnauthorized
+		if errors.Is(err, user.ErrEmailNotVerified) || errors.Is(err, user.ErrUserDeactivated) {
+			status = http.StatusForbidden
+		}
+		appError := error_response.NewAppError(err.Error(), status)
 		h.sendErrorResponse(w, appError)
 		return
 	}
-	newUser.Password = string(hashedPassword)
 
-	createdUser, err := h.useCase.Create(newUser)
+	appResponse := response.NewAppResponse(map[string]interface{}{
+		"user":                     signedInUser,
+		"token":                    tokens.AccessToken,
+		"token_expires_at":         tokens.AccessTokenExpiresAt,
+		"refresh_token":            tokens.RefreshToken,
+		"refresh_token_expires_at": tokens.RefreshTokenExpiresAt,
+	}, "User signed in successfully", nil)
+	h.sendSuccessResponse(w, appResponse)
+}
+
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var refreshData struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&refreshData)
+	if err != nil || refreshData.RefreshToken == "" {
+		appError := error_response.NewAppError("Refresh token is required", http.StatusBadRequest)
+		h.sendErrorResponse(w, appError)
+		return
+	}
+
+	tokens, err := h.useCase.Refresh(refreshData.RefreshToken)
 	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, user.ErrInvalidRefreshToken) || errors.Is(err, user.ErrRefreshTokenReused) {
+			status = http.StatusUnauthorized
+		}
+		appError := error_response.NewAppError(err.Error(), status)
+		h.sendErrorResponse(w, appError)
+		return
+	}
+
+	appResponse := response.NewAppResponse(tokens, "Token refreshed successfully", nil)
+	h.sendSuccessResponse(w, appResponse)
+}
+
+func (h *AuthHandler) SignOut(w http.ResponseWriter, r *http.Request) {
+	sessionID, ok := utils.GetSessionIDFromContext(r.Context())
+	if !ok {
+		appError := error_response.NewAppError("Session not found", http.StatusUnauthorized)
+		h.sendErrorResponse(w, appError)
+		return
+	}
+
+	if err := h.useCase.SignOut(sessionID); err != nil {
 		appError := error_response.NewAppError(err.Error(), http.StatusInternalServerError)
 		h.sendErrorResponse(w, appError)
 		return
 	}
 
-	appResponse := response.NewAppResponse(createdUser, "User created successfully", nil)
+	appResponse := response.NewAppResponse(nil, "User signed out successfully", nil)
 	h.sendSuccessResponse(w, appResponse)
 }
 
-func (h *AuthHandler) SignIn(w http.ResponseWriter, r *http.Request) {
-	var loginData struct {
-		Email    string `json:"email"`
+func (h *AuthHandler) SignOutAll(w http.ResponseWriter, r *http.Request) {
+	userID, ok := utils.GetUserIDFromContext(r.Context())
+	if !ok {
+		appError := error_response.NewAppError("User not found", http.StatusUnauthorized)
+		h.sendErrorResponse(w, appError)
+		return
+	}
+
+	if err := h.useCase.SignOutAll(userID); err != nil {
+		appError := error_response.NewAppError(err.Error(), http.StatusInternalServerError)
+		h.sendErrorResponse(w, appError)
+		return
+	}
+
+	appResponse := response.NewAppResponse(nil, "All sessions signed out successfully", nil)
+	h.sendSuccessResponse(w, appResponse)
+}
+
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var forgotData struct {
+		Email string `json:"email"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&forgotData)
+	if err != nil || forgotData.Email == "" {
+		appError := error_response.NewAppError("Email is required", http.StatusBadRequest)
+		h.sendErrorResponse(w, appError)
+		return
+	}
+
+	if err := h.useCase.RequestPasswordReset(forgotData.Email); err != nil {
+		appError := error_response.NewAppError(err.Error(), http.StatusInternalServerError)
+		h.sendErrorResponse(w, appError)
+		return
+	}
+
+	appResponse := response.NewAppResponse(nil, "If the email is registered, a reset link was sent", nil)
+	h.sendSuccessResponse(w, appResponse)
+}
+
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var resetData struct {
+		Token    string `json:"token"`
 		Password string `json:"password"`
 	}
 
-	err := json.NewDecoder(r.Body).Decode(&loginData)
-	if err != nil {
-		appError := error_response.NewAppError("Invalid input data", http.StatusBadRequest)
+	err := json.NewDecoder(r.Body).Decode(&resetData)
+	if err != nil || resetData.Token == "" || resetData.Password == "" {
+		appError := error_response.NewAppError("Token and password are required", http.StatusBadRequest)
 		h.sendErrorResponse(w, appError)
 		return
 	}
 
-	if loginData.Email == "" || loginData.Password == "" {
-		appError := error_response.NewAppError("Email and password are required", http.StatusBadRequest)
+	if err := h.useCase.ResetPassword(resetData.Token, resetData.Password); err != nil {
+		var validation *user.ValidationError
+		if errors.As(err, &validation) {
+			appError := error_response.NewAppError("Invalid password", http.StatusBadRequest).WithErrors(validation.Errors)
+			h.sendErrorResponse(w, appError)
+			return
+		}
+		status := http.StatusInternalServerError
+		if errors.Is(err, user.ErrInvalidUserToken) {
+			status = http.StatusBadRequest
+		}
+		appError := error_response.NewAppError(err.Error(), status)
 		h.sendErrorResponse(w, appError)
 		return
 	}
 
-	user, token, err := h.useCase.SignInUseCase(loginData.Email, loginData.Password)
-	if err != nil {
-		appError := error_response.NewAppError(err.Error(), http.StatusUnauthorized)
+	appResponse := response.NewAppResponse(nil, "Password reset successfully", nil)
+	h.sendSuccessResponse(w, appResponse)
+}
+
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var verifyData struct {
+		Token string `json:"token"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&verifyData)
+	if err != nil || verifyData.Token == "" {
+		appError := error_response.NewAppError("Token is required", http.StatusBadRequest)
 		h.sendErrorResponse(w, appError)
 		return
 	}
 
-	appResponse := response.NewAppResponse(map[string]interface{}{
-		"user":  user,
-		"token": token,
-	}, "User signed in successfully", nil)
+	if err := h.useCase.VerifyEmail(verifyData.Token); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, user.ErrInvalidUserToken) {
+			status = http.StatusBadRequest
+		}
+		appError := error_response.NewAppError(err.Error(), status)
+		h.sendErrorResponse(w, appError)
+		return
+	}
+
+	appResponse := response.NewAppResponse(nil, "Email verified successfully", nil)
+	h.sendSuccessResponse(w, appResponse)
+}
+
+func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var resendData struct {
+		Email string `json:"email"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&resendData)
+	if err != nil || resendData.Email == "" {
+		appError := error_response.NewAppError("Email is required", http.StatusBadRequest)
+		h.sendErrorResponse(w, appError)
+		return
+	}
+
+	if err := h.useCase.RequestEmailVerification(resendData.Email); err != nil {
+		appError := error_response.NewAppError(err.Error(), http.StatusInternalServerError)
+		h.sendErrorResponse(w, appError)
+		return
+	}
+
+	appResponse := response.NewAppResponse(nil, "If the email is registered and not verified, a verification link was sent", nil)
 	h.sendSuccessResponse(w, appResponse)
 }
 
+// clientIP is the address of the caller, as resolved by the ClientIPFrom*
+// middleware installed in app.go. Requests whose address could not be
+// trusted share a single bucket rather than being left unthrottled.
+func clientIP(r *http.Request) string {
+	if ip := middleware.GetClientIP(r.Context()); ip != "" {
+		return ip
+	}
+	return "unknown"
+}
+
 func (h *AuthHandler) sendErrorResponse(w http.ResponseWriter, appError error_response.AppError) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(appError.StatusCode)
@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/reinaldo-silva/savina-stock/internal/domain/user"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/user/usertest"
+	memory_login_attempt "github.com/reinaldo-silva/savina-stock/internal/infrastructure/login_attempt/memory"
+)
+
+func newTestAuthHandler(t *testing.T) (*AuthHandler, *usertest.UserRepository) {
+	t.Helper()
+	t.Setenv("PASSWORD_MIN_LENGTH", "8")
+	t.Setenv("PASSWORD_REQUIRE_LETTER", "true")
+	t.Setenv("PASSWORD_REQUIRE_UPPERCASE", "false")
+	t.Setenv("PASSWORD_REQUIRE_DIGIT", "true")
+	t.Setenv("PASSWORD_REQUIRE_SYMBOL", "false")
+
+	repo := usertest.NewUserRepository()
+	sessions := usertest.NewSessionRepository()
+	uc := user.NewUserUseCase(repo, sessions, usertest.NewUserTokenRepository(repo, sessions), memory_login_attempt.NewMemoryLoginAttemptStore(), nil)
+	return NewAuthHandler(uc), repo
+}
+
+func signUp(t *testing.T, h *AuthHandler, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/auth/sign-up", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	h.SignUp(w, r)
+	return w
+}
+
+func TestSignUpIgnoresTheRoleInTheBody(t *testing.T) {
+	h, repo := newTestAuthHandler(t)
+
+	w := signUp(t, h, `{"name":"Ana","email":"ana@example.com","password":"secret123","role":"ADMIN"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+
+	var body struct {
+		Data user.UserResponse `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Data.Role != user.ClientRole {
+		t.Fatalf("got role %s in the response, want CLIENT", body.Data.Role)
+	}
+
+	stored, err := repo.FindByEmail("ana@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Role != user.ClientRole {
+		t.Fatalf("stored role %s, want CLIENT", stored.Role)
+	}
+}
+
+func TestSignUpReportsEachInvalidField(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		field string
+	}{
+		{"missing name", `{"email":"ana@example.com","password":"secret123"}`, "name"},
+		{"short name", `{"name":"A","email":"ana@example.com","password":"secret123"}`, "name"},
+		{"missing email", `{"name":"Ana","password":"secret123"}`, "email"},
+		{"invalid email", `{"name":"Ana","email":"ana@example","password":"secret123"}`, "email"},
+		{"display name email", `{"name":"Ana","email":"Ana <ana@example.com>","password":"secret123"}`, "email"},
+		{"short password", `{"name":"Ana","email":"ana@example.com","password":"se1"}`, "password"},
+		{"password without digit", `{"name":"Ana","email":"ana@example.com","password":"secretpassword"}`, "password"},
+		{"password without letter", `{"name":"Ana","email":"ana@example.com","password":"12345678"}`, "password"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := newTestAuthHandler(t)
+
+			w := signUp(t, h, tt.body)
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("got status %d, want 400", w.Code)
+			}
+
+			var body struct {
+				Errors []user.FieldError `json:"errors"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if len(body.Errors) == 0 {
+				t.Fatal("no field errors in the response")
+			}
+			for _, fieldError := range body.Errors {
+				if fieldError.Field != tt.field {
+					t.Fatalf("got an error for %s (%s), want only %s", fieldError.Field, fieldError.Message, tt.field)
+				}
+			}
+		})
+	}
+}
+
+func TestSignUpRejectsATakenEmail(t *testing.T) {
+	h, _ := newTestAuthHandler(t)
+
+	if w := signUp(t, h, `{"name":"Ana","email":"ana@example.com","password":"secret123"}`); w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+
+	w := signUp(t, h, `{"name":"Ana","email":" ANA@example.com","password":"secret123"}`)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"field":"email"`) {
+		t.Fatalf("got status %d: %s, want an email error", w.Code, w.Body)
+	}
+}
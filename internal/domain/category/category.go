@@ -1,19 +1,102 @@
 package category
 
-import "time"
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/reinaldo-silva/savina-stock/utils"
+	"github.com/segmentio/ksuid"
+)
+
+const maxSlugLength = 140
+
+var (
+	ErrCategoryNotFound    = errors.New("category not found")
+	ErrParentNotFound      = errors.New("parent category not found")
+	ErrCategoryCycle       = errors.New("a category cannot be moved under itself or one of its descendants")
+	ErrCategoryHasChildren = errors.New("cannot delete category, it has subcategories")
+	ErrCategoryHasNoImage  = errors.New("category has no image")
+	ErrInvalidReorder      = errors.New("reorder needs a list of distinct category ids")
+)
+
+// ReservedSlugs are the paths under /category that would shadow a category
+// with the same slug.
+var ReservedSlugs = map[string]bool{"tree": true, "reorder": true}
 
 type Category struct {
-	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
-	Name      string    `gorm:"type:varchar(255);unique;not null" json:"name"`
-	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+	ID            uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name          string     `gorm:"type:varchar(255);unique;not null" json:"name"`
+	Slug          string     `gorm:"type:varchar(150);uniqueIndex" json:"slug"`
+	Description   string     `gorm:"type:text" json:"description"`
+	ImagePublicID string     `gorm:"type:varchar(255)" json:"-"`
+	ImageURL      string     `gorm:"-" json:"image_url,omitempty"`
+	Position      int        `gorm:"not null;default:0;index" json:"position"`
+	ParentID      *uint      `gorm:"index" json:"parent_id"`
+	Children      []Category `gorm:"foreignKey:ParentID;constraint:OnDelete:RESTRICT" json:"children,omitempty"`
+	ProductCount  *int64     `gorm:"->;-:migration" json:"product_count,omitempty"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt     time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// UpdateCategoryRequest is the body of PUT /category/{id}. Fields left out
+// of the body keep their current value.
+type UpdateCategoryRequest struct {
+	Name        *string    `json:"name"`
+	Slug        *string    `json:"slug"`
+	Description *string    `json:"description"`
+	Position    *int       `json:"position"`
+	ParentID    OptionalID `json:"parent_id"`
+}
+
+// OptionalID tells a missing id apart from an explicit null, which for
+// parent_id moves the category to the root.
+type OptionalID struct {
+	Set   bool
+	Value *uint
+}
+
+func (o *OptionalID) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	return json.Unmarshal(data, &o.Value)
 }
 
 type CategoryRepository interface {
 	Create(category *Category) error
 	GetAll() ([]Category, error)
+	GetAllWithProductCount() ([]Category, error)
 	Delete(id uint) error
 	Update(category *Category) error
 	GetByID(id uint) (*Category, error)
+	GetBySlug(slug string) (*Category, error)
+	UniqueSlug(base string, categoryID uint, reserved map[string]bool) (string, error)
+	Reorder(ids []uint) error
 	HasProducts(id uint) (bool, error)
+	HasChildren(id uint) (bool, error)
+	GetDescendantIDs(id uint) ([]uint, error)
+}
+
+// GenerateSlug derives the slug from the category name, falling back to a
+// random id when the name has no usable characters.
+func GenerateSlug(name string) string {
+	slug := utils.Slugify(name)
+	if len(slug) > maxSlugLength {
+		slug = strings.TrimRight(slug[:maxSlugLength], "-")
+	}
+	if slug == "" {
+		id := ksuid.New().String()
+		return strings.ToLower(id[:8])
+	}
+	return slug
+}
+
+// SetImageURL fills ImageURL for the category and its children.
+func (c *Category) SetImageURL(host string) {
+	if c.ImagePublicID != "" {
+		c.ImageURL = utils.GenerateCategoryImageURL(host, c.Slug)
+	}
+	for i := range c.Children {
+		c.Children[i].SetImageURL(host)
+	}
 }
@@ -2,7 +2,10 @@ package category
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"os"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
@@ -31,7 +34,11 @@ func (h *CategoryHandler) CreateCategory(w http.ResponseWriter, r *http.Request)
 
 	createdCategory, err := h.useCase.CreateCategory(&category)
 	if err != nil {
-		appError := error.NewAppError(err.Error(), http.StatusInternalServerError)
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrParentNotFound) {
+			status = http.StatusBadRequest
+		}
+		appError := error.NewAppError(err.Error(), status)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(appError.StatusCode)
 		json.NewEncoder(w).Encode(appError)
@@ -46,7 +53,7 @@ func (h *CategoryHandler) CreateCategory(w http.ResponseWriter, r *http.Request)
 
 func (h *CategoryHandler) GetAllCategories(w http.ResponseWriter, r *http.Request) {
 
-	categories, err := h.useCase.GetAllCategories()
+	categories, err := h.useCase.GetAllCategories(r.Host)
 	if err != nil {
 		appError := error.NewAppError("Failed to fetch categories", http.StatusInternalServerError)
 		w.Header().Set("Content-Type", "application/json")
@@ -61,6 +68,23 @@ func (h *CategoryHandler) GetAllCategories(w http.ResponseWriter, r *http.Reques
 	json.NewEncoder(w).Encode(appResponse)
 }
 
+func (h *CategoryHandler) GetCategoryTree(w http.ResponseWriter, r *http.Request) {
+
+	tree, err := h.useCase.GetCategoryTree(r.Host)
+	if err != nil {
+		appError := error.NewAppError("Failed to fetch categories", http.StatusInternalServerError)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	appResponse := response.NewAppResponse(tree, "Category tree fetched successfully", nil)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(appResponse)
+}
+
 func (h *CategoryHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
@@ -75,7 +99,13 @@ func (h *CategoryHandler) DeleteCategory(w http.ResponseWriter, r *http.Request)
 
 	err = h.useCase.DeleteCategory(uint(categoryID))
 	if err != nil {
-		appError := error.NewAppError(err.Error(), http.StatusInternalServerError)
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrCategoryHasChildren) {
+			status = http.StatusConflict
+		} else if errors.Is(err, ErrCategoryNotFound) {
+			status = http.StatusNotFound
+		}
+		appError := error.NewAppError(err.Error(), status)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(appError.StatusCode)
 		json.NewEncoder(w).Encode(appError)
@@ -88,7 +118,25 @@ func (h *CategoryHandler) DeleteCategory(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(appResponse)
 }
 
-func (h *CategoryHandler) GetCategoryByID(w http.ResponseWriter, r *http.Request) {
+func (h *CategoryHandler) GetCategoryBySlug(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "slug")
+
+	category, err := h.useCase.GetCategoryBySlug(slug, r.Host)
+	if err != nil {
+		appError := error.NewAppError("Category not found", http.StatusNotFound)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	appResponse := response.NewAppResponse(category, "Category fetched successfully", nil, http.StatusOK)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(appResponse.StatusCode)
+	json.NewEncoder(w).Encode(appResponse)
+}
+
+func (h *CategoryHandler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
 	categoryID, err := strconv.Atoi(id)
@@ -100,25 +148,76 @@ func (h *CategoryHandler) GetCategoryByID(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	category, err := h.useCase.GetCategoryByID(uint(categoryID))
+	var updateData UpdateCategoryRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&updateData); err != nil {
+		appError := error.NewAppError("Invalid request payload", http.StatusBadRequest)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	updatedCategory, err := h.useCase.UpdateCategory(uint(categoryID), updateData)
 	if err != nil {
-		appError := error.NewAppError("Category not found", http.StatusNotFound)
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrParentNotFound) || errors.Is(err, ErrCategoryCycle) {
+			status = http.StatusBadRequest
+		}
+		if errors.Is(err, ErrCategoryNotFound) {
+			status = http.StatusNotFound
+		}
+		appError := error.NewAppError(err.Error(), status)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(appError.StatusCode)
 		json.NewEncoder(w).Encode(appError)
 		return
 	}
 
-	appResponse := response.NewAppResponse(category, "Category fetched successfully", nil, http.StatusOK)
+	appResponse := response.NewAppResponse(updatedCategory, "Category updated successfully", nil, http.StatusOK)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(appResponse.StatusCode)
 	json.NewEncoder(w).Encode(appResponse)
 }
 
-func (h *CategoryHandler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
-	id := chi.URLParam(r, "id")
+type reorderCategoriesRequest struct {
+	IDs []uint `json:"ids"`
+}
 
-	categoryID, err := strconv.Atoi(id)
+func (h *CategoryHandler) ReorderCategories(w http.ResponseWriter, r *http.Request) {
+	var request reorderCategoriesRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		appError := error.NewAppError("Invalid request payload", http.StatusBadRequest)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	err := h.useCase.Reorder(request.IDs)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrInvalidReorder) {
+			status = http.StatusBadRequest
+		} else if errors.Is(err, ErrCategoryNotFound) {
+			status = http.StatusNotFound
+		}
+		appError := error.NewAppError(err.Error(), status)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	appResponse := response.NewAppResponse(nil, "Categories reordered successfully", nil, http.StatusOK)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(appResponse.StatusCode)
+	json.NewEncoder(w).Encode(appResponse)
+}
+
+func (h *CategoryHandler) UploadCategoryImage(w http.ResponseWriter, r *http.Request) {
+	categoryID, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
 		appError := error.NewAppError("Invalid category ID format", http.StatusBadRequest)
 		w.Header().Set("Content-Type", "application/json")
@@ -127,28 +226,97 @@ func (h *CategoryHandler) UpdateCategory(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	var updatedCategory Category
+	file, _, err := r.FormFile("image")
+	if err != nil {
+		appError := error.NewAppError("The image file is required", http.StatusBadRequest)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+	defer file.Close()
+
+	tempFile, err := os.CreateTemp("", "upload-*.png")
+	if err != nil {
+		appError := error.NewAppError("Failed to create temp file", http.StatusInternalServerError)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+	defer os.Remove(tempFile.Name())
+	defer tempFile.Close()
 
-	if err := json.NewDecoder(r.Body).Decode(&updatedCategory); err != nil {
-		appError := error.NewAppError("Invalid request payload", http.StatusBadRequest)
+	if _, err := io.Copy(tempFile, file); err != nil {
+		appError := error.NewAppError("Failed to save the image", http.StatusInternalServerError)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(appError.StatusCode)
 		json.NewEncoder(w).Encode(appError)
 		return
 	}
 
-	updatedCategory.ID = uint(categoryID)
-	err = h.useCase.UpdateCategory(&updatedCategory)
+	category, err := h.useCase.SetCategoryImage(uint(categoryID), tempFile.Name(), r.Host)
 	if err != nil {
-		appError := error.NewAppError(err.Error(), http.StatusInternalServerError)
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrCategoryNotFound) {
+			status = http.StatusNotFound
+		}
+		appError := error.NewAppError(err.Error(), status)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(appError.StatusCode)
 		json.NewEncoder(w).Encode(appError)
 		return
 	}
 
-	appResponse := response.NewAppResponse(updatedCategory, "Category updated successfully", nil, http.StatusOK)
+	appResponse := response.NewAppResponse(category, "Category image uploaded successfully", nil, http.StatusOK)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(appResponse.StatusCode)
 	json.NewEncoder(w).Encode(appResponse)
 }
+
+func (h *CategoryHandler) DeleteCategoryImage(w http.ResponseWriter, r *http.Request) {
+	categoryID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		appError := error.NewAppError("Invalid category ID format", http.StatusBadRequest)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	err = h.useCase.DeleteCategoryImage(uint(categoryID))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrCategoryNotFound) || errors.Is(err, ErrCategoryHasNoImage) {
+			status = http.StatusNotFound
+		}
+		appError := error.NewAppError(err.Error(), status)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	appResponse := response.NewAppResponse(nil, "Category image deleted successfully", nil, http.StatusOK)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(appResponse.StatusCode)
+	json.NewEncoder(w).Encode(appResponse)
+}
+
+func (h *CategoryHandler) GetCategoryImage(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "slug")
+
+	imageBuffer, contentType, err := h.useCase.GetCategoryImage(slug)
+	if err != nil {
+		http.Error(w, "Image not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", "inline")
+
+	if _, err := io.Copy(w, imageBuffer); err != nil {
+		http.Error(w, "Failed to send the image", http.StatusInternalServerError)
+		return
+	}
+}
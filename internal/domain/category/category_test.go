@@ -0,0 +1,35 @@
+package category
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestUpdateCategoryRequestTellsMissingFromNull(t *testing.T) {
+	var missing UpdateCategoryRequest
+	if err := json.Unmarshal([]byte(`{"name":"Bolsas"}`), &missing); err != nil {
+		t.Fatal(err)
+	}
+	if missing.ParentID.Set || missing.Description != nil || missing.Position != nil {
+		t.Fatalf("fields left out of the body were set: %+v", missing)
+	}
+
+	var null UpdateCategoryRequest
+	if err := json.Unmarshal([]byte(`{"parent_id":null}`), &null); err != nil {
+		t.Fatal(err)
+	}
+	if !null.ParentID.Set || null.ParentID.Value != nil {
+		t.Fatalf("got %+v, want a set null parent_id", null.ParentID)
+	}
+
+	var parent UpdateCategoryRequest
+	if err := json.Unmarshal([]byte(`{"parent_id":7,"position":0}`), &parent); err != nil {
+		t.Fatal(err)
+	}
+	if !parent.ParentID.Set || parent.ParentID.Value == nil || *parent.ParentID.Value != 7 {
+		t.Fatalf("got %+v, want parent_id 7", parent.ParentID)
+	}
+	if parent.Position == nil || *parent.Position != 0 {
+		t.Fatalf("got position %v, want an explicit 0", parent.Position)
+	}
+}
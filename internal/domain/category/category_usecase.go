@@ -1,15 +1,20 @@
 package category
 
 import (
+	"bytes"
 	"fmt"
+	"strconv"
+
+	"github.com/reinaldo-silva/savina-stock/internal/domain/image_service"
 )
 
 type CategoryUseCase struct {
-	repo CategoryRepository
+	repo         CategoryRepository
+	imageService *image_service.ImageService
 }
 
-func NewCategoryUseCase(repo CategoryRepository) *CategoryUseCase {
-	return &CategoryUseCase{repo: repo}
+func NewCategoryUseCase(repo CategoryRepository, imageService *image_service.ImageService) *CategoryUseCase {
+	return &CategoryUseCase{repo: repo, imageService: imageService}
 }
 
 func (uc *CategoryUseCase) CreateCategory(category *Category) (*Category, error) {
@@ -17,7 +22,25 @@ func (uc *CategoryUseCase) CreateCategory(category *Category) (*Category, error)
 		return nil, fmt.Errorf("category name is required")
 	}
 
-	err := uc.repo.Create(category)
+	if category.ParentID != nil {
+		if _, err := uc.repo.GetByID(*category.ParentID); err != nil {
+			return nil, ErrParentNotFound
+		}
+	}
+
+	base := GenerateSlug(category.Name)
+	if category.Slug != "" {
+		base = GenerateSlug(category.Slug)
+	}
+	slug, err := uc.repo.UniqueSlug(base, 0, ReservedSlugs)
+	if err != nil {
+		return nil, err
+	}
+
+	category.Slug = slug
+	category.ImagePublicID = ""
+	category.Children = nil
+	err = uc.repo.Create(category)
 	if err != nil {
 		return nil, err
 	}
@@ -25,44 +48,201 @@ func (uc *CategoryUseCase) CreateCategory(category *Category) (*Category, error)
 	return category, nil
 }
 
-func (uc *CategoryUseCase) GetAllCategories() ([]Category, error) {
-	categories, err := uc.repo.GetAll()
+func (uc *CategoryUseCase) GetAllCategories(host string) ([]Category, error) {
+	categories, err := uc.repo.GetAllWithProductCount()
 	if err != nil {
 		return nil, err
 	}
+
+	for i := range categories {
+		categories[i].SetImageURL(host)
+	}
 	return categories, nil
 }
 
+// GetCategoryTree returns the root categories with their subcategories
+// nested under Children.
+func (uc *CategoryUseCase) GetCategoryTree(host string) ([]Category, error) {
+	categories, err := uc.GetAllCategories(host)
+	if err != nil {
+		return nil, err
+	}
+
+	childrenOf := make(map[uint][]Category)
+	var roots []Category
+	for _, c := range categories {
+		if c.ParentID == nil {
+			roots = append(roots, c)
+		} else {
+			childrenOf[*c.ParentID] = append(childrenOf[*c.ParentID], c)
+		}
+	}
+
+	var attach func(nodes []Category) []Category
+	attach = func(nodes []Category) []Category {
+		for i := range nodes {
+			nodes[i].Children = attach(childrenOf[nodes[i].ID])
+		}
+		return nodes
+	}
+
+	return attach(roots), nil
+}
+
 func (uc *CategoryUseCase) GetCategoryByID(id uint) (*Category, error) {
 	category, err := uc.repo.GetByID(id)
 	if err != nil {
-		return nil, fmt.Errorf("category not found")
+		return nil, ErrCategoryNotFound
+	}
+	return category, nil
+}
+
+// GetCategoryBySlug also accepts the numeric id the categories were fetched
+// by before they had slugs.
+func (uc *CategoryUseCase) GetCategoryBySlug(slug string, host string) (*Category, error) {
+	category, err := uc.repo.GetBySlug(slug)
+	if err != nil {
+		id, convErr := strconv.ParseUint(slug, 10, 32)
+		if convErr != nil {
+			return nil, ErrCategoryNotFound
+		}
+		if category, err = uc.repo.GetByID(uint(id)); err != nil {
+			return nil, ErrCategoryNotFound
+		}
 	}
+
+	category.SetImageURL(host)
 	return category, nil
 }
 
-func (uc *CategoryUseCase) UpdateCategory(updatedCategory *Category) error {
-	existingCategory, err := uc.repo.GetByID(updatedCategory.ID)
+// Reorder sets the position of the given categories to their index in ids,
+// so siblings can be sorted in one request.
+func (uc *CategoryUseCase) Reorder(ids []uint) error {
+	seen := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			return ErrInvalidReorder
+		}
+		seen[id] = true
+	}
+	if len(ids) == 0 {
+		return ErrInvalidReorder
+	}
+
+	return uc.repo.Reorder(ids)
+}
+
+// SetCategoryImage uploads the file at filePath as the category cover,
+// replacing the previous one.
+func (uc *CategoryUseCase) SetCategoryImage(id uint, filePath string, host string) (*Category, error) {
+	category, err := uc.repo.GetByID(id)
 	if err != nil {
-		return fmt.Errorf("category not found")
+		return nil, ErrCategoryNotFound
 	}
 
-	if updatedCategory.Name == "" {
-		return fmt.Errorf("category name is required")
+	publicID, err := uc.imageService.Upload(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload the image: %v", err)
 	}
 
-	existingCategory.Name = updatedCategory.Name
-	err = uc.repo.Update(existingCategory)
+	previousPublicID := category.ImagePublicID
+	category.ImagePublicID = publicID
+	if err := uc.repo.Update(category); err != nil {
+		uc.imageService.DeleteImage(publicID)
+		return nil, err
+	}
+
+	if previousPublicID != "" {
+		uc.imageService.DeleteImage(previousPublicID)
+	}
+
+	category.SetImageURL(host)
+	return category, nil
+}
+
+func (uc *CategoryUseCase) DeleteCategoryImage(id uint) error {
+	category, err := uc.repo.GetByID(id)
 	if err != nil {
+		return ErrCategoryNotFound
+	}
+	if category.ImagePublicID == "" {
+		return ErrCategoryHasNoImage
+	}
+
+	publicID := category.ImagePublicID
+	category.ImagePublicID = ""
+	if err := uc.repo.Update(category); err != nil {
 		return err
 	}
-	return nil
+
+	return uc.imageService.DeleteImage(publicID)
+}
+
+func (uc *CategoryUseCase) GetCategoryImage(slug string) (*bytes.Buffer, string, error) {
+	category, err := uc.repo.GetBySlug(slug)
+	if err != nil {
+		return nil, "", ErrCategoryNotFound
+	}
+	if category.ImagePublicID == "" {
+		return nil, "", ErrCategoryHasNoImage
+	}
+
+	return uc.imageService.Download(category.ImagePublicID)
+}
+
+// UpdateCategory applies the fields present in the request, leaving the
+// others untouched.
+func (uc *CategoryUseCase) UpdateCategory(id uint, req UpdateCategoryRequest) (*Category, error) {
+	existingCategory, err := uc.repo.GetByID(id)
+	if err != nil {
+		return nil, ErrCategoryNotFound
+	}
+
+	if req.Name != nil {
+		if *req.Name == "" {
+			return nil, fmt.Errorf("category name is required")
+		}
+		existingCategory.Name = *req.Name
+	}
+
+	if req.ParentID.Set {
+		if req.ParentID.Value != nil {
+			if err := uc.checkParent(id, *req.ParentID.Value); err != nil {
+				return nil, err
+			}
+		}
+		existingCategory.ParentID = req.ParentID.Value
+	}
+
+	// The slug only changes when a new one is sent, so links to the
+	// category survive a rename.
+	if req.Slug != nil && *req.Slug != "" && *req.Slug != existingCategory.Slug {
+		slug, err := uc.repo.UniqueSlug(GenerateSlug(*req.Slug), existingCategory.ID, ReservedSlugs)
+		if err != nil {
+			return nil, err
+		}
+		existingCategory.Slug = slug
+	}
+
+	if req.Description != nil {
+		existingCategory.Description = *req.Description
+	}
+	if req.Position != nil {
+		existingCategory.Position = *req.Position
+	}
+
+	err = uc.repo.Update(existingCategory)
+	if err != nil {
+		return nil, err
+	}
+
+	return existingCategory, nil
 }
 
 func (uc *CategoryUseCase) DeleteCategory(id uint) error {
-	_, err := uc.repo.GetByID(id)
+	category, err := uc.repo.GetByID(id)
 	if err != nil {
-		return fmt.Errorf("category not found")
+		return ErrCategoryNotFound
 	}
 
 	hasProducts, err := uc.repo.HasProducts(id)
@@ -73,9 +253,45 @@ func (uc *CategoryUseCase) DeleteCategory(id uint) error {
 		return fmt.Errorf("cannot delete category, products are associated with it")
 	}
 
+	hasChildren, err := uc.repo.HasChildren(id)
+	if err != nil {
+		return fmt.Errorf("error checking subcategories: %v", err)
+	}
+	if hasChildren {
+		return ErrCategoryHasChildren
+	}
+
 	err = uc.repo.Delete(id)
 	if err != nil {
 		return err
 	}
+
+	if category.ImagePublicID != "" {
+		uc.imageService.DeleteImage(category.ImagePublicID)
+	}
+	return nil
+}
+
+// checkParent refuses a parent that does not exist or that would make the
+// category an ancestor of itself.
+func (uc *CategoryUseCase) checkParent(id uint, parentID uint) error {
+	if parentID == id {
+		return ErrCategoryCycle
+	}
+
+	if _, err := uc.repo.GetByID(parentID); err != nil {
+		return ErrParentNotFound
+	}
+
+	descendants, err := uc.repo.GetDescendantIDs(id)
+	if err != nil {
+		return err
+	}
+	for _, descendantID := range descendants {
+		if descendantID == parentID {
+			return ErrCategoryCycle
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,62 @@
+package inventory_count
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+type Status string
+
+const (
+	OpenStatus      Status = "OPEN"
+	CommittedStatus Status = "COMMITTED"
+	CanceledStatus  Status = "CANCELED"
+)
+
+var (
+	ErrInventoryCountNotFound = errors.New("inventory count not found")
+	ErrInventoryCountNotOpen  = errors.New("inventory count is not open")
+	ErrInventoryCountEmpty    = errors.New("inventory count has no counted items")
+)
+
+type InventoryCount struct {
+	ID          uint                 `gorm:"primaryKey;autoIncrement" json:"id"`
+	Status      Status               `gorm:"type:varchar(20);not null;default:OPEN;index" json:"status"`
+	Notes       string               `gorm:"type:text" json:"notes"`
+	UserID      uint                 `gorm:"index" json:"user_id"`
+	CommittedAt *time.Time           `json:"committed_at"`
+	CreatedAt   time.Time            `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time            `gorm:"autoUpdateTime" json:"updated_at"`
+	Items       []InventoryCountItem `gorm:"foreignKey:InventoryCountID" json:"items,omitempty"`
+}
+
+type InventoryCountItem struct {
+	ID               uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	InventoryCountID uint      `gorm:"not null;uniqueIndex:idx_inventory_count_product" json:"inventory_count_id"`
+	ProductID        uint      `gorm:"not null;uniqueIndex:idx_inventory_count_product" json:"product_id"`
+	CountedQuantity  int       `gorm:"not null" json:"counted_quantity"`
+	ExpectedQuantity *int      `json:"expected_quantity"` // Product stock when the session was committed
+	Variance         *int      `json:"variance"`
+	CreatedAt        time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt        time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+type VarianceLine struct {
+	ProductID        uint   `json:"product_id"`
+	Slug             string `json:"slug"`
+	Name             string `json:"name"`
+	ExpectedQuantity int    `json:"expected_quantity"`
+	CountedQuantity  int    `json:"counted_quantity"`
+	Variance         int    `json:"variance"`
+}
+
+type InventoryCountRepository interface {
+	GetAll(ctx context.Context, page int, pageSize int) ([]InventoryCount, int64, error)
+	FindByID(id uint) (*InventoryCount, error)
+	Create(ctx context.Context, count *InventoryCount) error
+	SaveItems(ctx context.Context, countID uint, items []InventoryCountItem) error
+	GetVariance(ctx context.Context, countID uint) ([]VarianceLine, error)
+	Commit(ctx context.Context, countID uint) (*InventoryCount, error)
+	Cancel(ctx context.Context, countID uint) (*InventoryCount, error)
+}
@@ -0,0 +1,183 @@
+package inventory_count
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/product"
+	error_response "github.com/reinaldo-silva/savina-stock/package/response/error"
+	"github.com/reinaldo-silva/savina-stock/package/response/response"
+)
+
+type InventoryCountHandler struct {
+	useCase *InventoryCountUseCase
+}
+
+func NewInventoryCountHandler(uc *InventoryCountUseCase) *InventoryCountHandler {
+	return &InventoryCountHandler{uc}
+}
+
+func (h *InventoryCountHandler) GetInventoryCounts(w http.ResponseWriter, r *http.Request) {
+	pageStr := r.URL.Query().Get("page")
+	pageSizeStr := r.URL.Query().Get("pageSize")
+
+	page, err := strconv.Atoi(pageStr)
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(pageSizeStr)
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+
+	counts, total, err := h.useCase.GetAll(r.Context(), page, pageSize)
+	if err != nil {
+		h.sendErrorResponse(w, error_response.NewAppError(err.Error(), http.StatusInternalServerError))
+		return
+	}
+
+	appResponse := response.NewAppResponse(counts, "Inventory counts fetched successfully", &total)
+	h.sendSuccessResponse(w, appResponse)
+}
+
+func (h *InventoryCountHandler) GetInventoryCountByID(w http.ResponseWriter, r *http.Request) {
+	countID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.sendErrorResponse(w, error_response.NewAppError("Invalid inventory count ID format", http.StatusBadRequest))
+		return
+	}
+
+	count, err := h.useCase.GetByID(uint(countID))
+	if err != nil {
+		h.sendErrorResponse(w, error_response.NewAppError(err.Error(), errorStatusCode(err)))
+		return
+	}
+
+	appResponse := response.NewAppResponse(count, "Inventory count fetched successfully", nil)
+	h.sendSuccessResponse(w, appResponse)
+}
+
+func (h *InventoryCountHandler) OpenInventoryCount(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Notes string `json:"notes"`
+	}
+
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			h.sendErrorResponse(w, error_response.NewAppError("Invalid request payload", http.StatusBadRequest))
+			return
+		}
+	}
+
+	count, err := h.useCase.Open(r.Context(), body.Notes)
+	if err != nil {
+		h.sendErrorResponse(w, error_response.NewAppError(err.Error(), http.StatusInternalServerError))
+		return
+	}
+
+	appResponse := response.NewAppResponse(count, "Inventory count opened successfully", nil, http.StatusCreated)
+	h.sendSuccessResponse(w, appResponse)
+}
+
+func (h *InventoryCountHandler) SubmitItems(w http.ResponseWriter, r *http.Request) {
+	countID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.sendErrorResponse(w, error_response.NewAppError("Invalid inventory count ID format", http.StatusBadRequest))
+		return
+	}
+
+	var body struct {
+		Items []CountedItem `json:"items"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		h.sendErrorResponse(w, error_response.NewAppError("Invalid request payload", http.StatusBadRequest))
+		return
+	}
+
+	count, err := h.useCase.SubmitItems(r.Context(), uint(countID), body.Items)
+	if err != nil {
+		h.sendErrorResponse(w, error_response.NewAppError(err.Error(), errorStatusCode(err)))
+		return
+	}
+
+	appResponse := response.NewAppResponse(count, "Counted items saved successfully", nil)
+	h.sendSuccessResponse(w, appResponse)
+}
+
+func (h *InventoryCountHandler) GetVariance(w http.ResponseWriter, r *http.Request) {
+	countID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.sendErrorResponse(w, error_response.NewAppError("Invalid inventory count ID format", http.StatusBadRequest))
+		return
+	}
+
+	lines, err := h.useCase.GetVariance(r.Context(), uint(countID))
+	if err != nil {
+		h.sendErrorResponse(w, error_response.NewAppError(err.Error(), errorStatusCode(err)))
+		return
+	}
+
+	appResponse := response.NewAppResponse(lines, "Inventory count variance fetched successfully", nil)
+	h.sendSuccessResponse(w, appResponse)
+}
+
+func (h *InventoryCountHandler) CommitInventoryCount(w http.ResponseWriter, r *http.Request) {
+	countID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.sendErrorResponse(w, error_response.NewAppError("Invalid inventory count ID format", http.StatusBadRequest))
+		return
+	}
+
+	count, err := h.useCase.Commit(r.Context(), uint(countID))
+	if err != nil {
+		h.sendErrorResponse(w, error_response.NewAppError(err.Error(), errorStatusCode(err)))
+		return
+	}
+
+	appResponse := response.NewAppResponse(count, "Inventory count committed successfully", nil)
+	h.sendSuccessResponse(w, appResponse)
+}
+
+func (h *InventoryCountHandler) CancelInventoryCount(w http.ResponseWriter, r *http.Request) {
+	countID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.sendErrorResponse(w, error_response.NewAppError("Invalid inventory count ID format", http.StatusBadRequest))
+		return
+	}
+
+	count, err := h.useCase.Cancel(r.Context(), uint(countID))
+	if err != nil {
+		h.sendErrorResponse(w, error_response.NewAppError(err.Error(), errorStatusCode(err)))
+		return
+	}
+
+	appResponse := response.NewAppResponse(count, "Inventory count canceled successfully", nil)
+	h.sendSuccessResponse(w, appResponse)
+}
+
+func errorStatusCode(err error) int {
+	switch {
+	case errors.Is(err, ErrInventoryCountNotFound), errors.Is(err, product.ErrProductNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrInventoryCountNotOpen):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+func (h *InventoryCountHandler) sendErrorResponse(w http.ResponseWriter, appError error_response.AppError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(appError.StatusCode)
+	json.NewEncoder(w).Encode(appError)
+}
+
+func (h *InventoryCountHandler) sendSuccessResponse(w http.ResponseWriter, appResponse response.AppResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(appResponse.StatusCode)
+	json.NewEncoder(w).Encode(appResponse)
+}
@@ -0,0 +1,125 @@
+package inventory_count
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/reinaldo-silva/savina-stock/internal/domain/product"
+	"github.com/reinaldo-silva/savina-stock/utils"
+)
+
+type CountedItem struct {
+	Slug            string `json:"slug"`
+	CountedQuantity int    `json:"counted_quantity"`
+}
+
+type InventoryCountUseCase struct {
+	repo        InventoryCountRepository
+	productRepo product.ProductRepository
+}
+
+func NewInventoryCountUseCase(repo InventoryCountRepository, productRepo product.ProductRepository) *InventoryCountUseCase {
+	return &InventoryCountUseCase{
+		repo:        repo,
+		productRepo: productRepo,
+	}
+}
+
+func (uc *InventoryCountUseCase) GetAll(ctx context.Context, page int, pageSize int) ([]InventoryCount, int64, error) {
+	return uc.repo.GetAll(ctx, page, pageSize)
+}
+
+func (uc *InventoryCountUseCase) GetByID(id uint) (*InventoryCount, error) {
+	return uc.repo.FindByID(id)
+}
+
+func (uc *InventoryCountUseCase) Open(ctx context.Context, notes string) (*InventoryCount, error) {
+	count := InventoryCount{
+		Status: OpenStatus,
+		Notes:  strings.TrimSpace(notes),
+	}
+
+	if userID, ok := utils.GetUserIDFromContext(ctx); ok {
+		count.UserID = userID
+	}
+
+	err := uc.repo.Create(ctx, &count)
+	if err != nil {
+		return nil, err
+	}
+
+	return &count, nil
+}
+
+func (uc *InventoryCountUseCase) SubmitItems(ctx context.Context, countID uint, countedItems []CountedItem) (*InventoryCount, error) {
+
+	if len(countedItems) == 0 {
+		return nil, errors.New("at least one counted item is required")
+	}
+
+	count, err := uc.repo.FindByID(countID)
+	if err != nil {
+		return nil, err
+	}
+
+	if count.Status != OpenStatus {
+		return nil, ErrInventoryCountNotOpen
+	}
+
+	itemsByProduct := make(map[uint]InventoryCountItem)
+	var productOrder []uint
+	for _, countedItem := range countedItems {
+		if countedItem.CountedQuantity < 0 {
+			return nil, fmt.Errorf("counted quantity for product %s cannot be negative", countedItem.Slug)
+		}
+
+		p, err := uc.productRepo.FindBySlug(strings.TrimSpace(countedItem.Slug))
+		if err != nil {
+			return nil, fmt.Errorf("product with slug %s not found: %w", countedItem.Slug, product.ErrProductNotFound)
+		}
+
+		if len(p.Variants) > 0 {
+			return nil, fmt.Errorf("product %s has variants and cannot be counted as a whole: %w", p.Slug, product.ErrProductHasVariants)
+		}
+
+		if _, exists := itemsByProduct[p.ID]; !exists {
+			productOrder = append(productOrder, p.ID)
+		}
+
+		itemsByProduct[p.ID] = InventoryCountItem{
+			InventoryCountID: countID,
+			ProductID:        p.ID,
+			CountedQuantity:  countedItem.CountedQuantity,
+		}
+	}
+
+	items := make([]InventoryCountItem, 0, len(productOrder))
+	for _, productID := range productOrder {
+		items = append(items, itemsByProduct[productID])
+	}
+
+	err = uc.repo.SaveItems(ctx, countID, items)
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.repo.FindByID(countID)
+}
+
+func (uc *InventoryCountUseCase) GetVariance(ctx context.Context, countID uint) ([]VarianceLine, error) {
+	if _, err := uc.repo.FindByID(countID); err != nil {
+		return nil, err
+	}
+
+	return uc.repo.GetVariance(ctx, countID)
+}
+
+func (uc *InventoryCountUseCase) Commit(ctx context.Context, countID uint) (*InventoryCount, error) {
+	return uc.repo.Commit(ctx, countID)
+}
+
+func (uc *InventoryCountUseCase) Cancel(ctx context.Context, countID uint) (*InventoryCount, error) {
+	return uc.repo.Cancel(ctx, countID)
+}
@@ -2,56 +2,146 @@ package product
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/reinaldo-silva/savina-stock/internal/domain/category"
 	"github.com/reinaldo-silva/savina-stock/internal/domain/product_image"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/product_variant"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/stock_movement"
+	"github.com/reinaldo-silva/savina-stock/package/pagination"
+	"github.com/reinaldo-silva/savina-stock/utils"
 
 	"github.com/segmentio/ksuid"
+	"gorm.io/gorm"
 )
 
+var (
+	ErrProductNotFound       = errors.New("product not found")
+	ErrInsufficientStock     = errors.New("insufficient stock")
+	ErrInvalidQuantity       = errors.New("quantity must be greater than zero")
+	ErrProductHasVariants    = errors.New("product has variants, stock must be moved per variant")
+	ErrDuplicateCode         = errors.New("sku or barcode already in use")
+	ErrDuplicateSlug         = errors.New("slug already in use")
+	ErrProductHasNoCode      = errors.New("product has no barcode or sku")
+	ErrInvalidProduct        = errors.New("invalid product data")
+	ErrProductNotInTrash     = errors.New("product not found in trash")
+	ErrProductHasSales       = errors.New("product has sales and cannot be permanently deleted")
+	ErrCursorWithSearch      = errors.New("cursor pagination is not available for text search, use page instead")
+	ErrInvalidMovementFilter = errors.New("invalid stock movement filter")
+)
+
+// ReservedSlugs are the paths under /products that would shadow a product
+// with the same slug.
+var ReservedSlugs = map[string]bool{
+	"to-admin":  true,
+	"low-stock": true,
+	"by-code":   true,
+	"import":    true,
+	"export":    true,
+	"trash":     true,
+}
+
+// maxSlugAttempts bounds the retries when another request takes the slug
+// between picking it and saving the product.
+const maxSlugAttempts = 3
+
+// Leaves room in the slug column for the uniqueness suffix.
+const maxSlugBaseLength = 140
+
 type Product struct {
-	ID          uint                         `gorm:"primaryKey;autoIncrement" json:"id"`
-	Name        string                       `gorm:"type:varchar(100);not null" json:"name"`
-	Slug        string                       `gorm:"type:varchar(150);unique;not null" json:"slug"`
-	Description string                       `gorm:"type:text" json:"description"`
-	Price       float64                      `gorm:"type:decimal(10,2);not null" json:"price"`
-	Cost        float64                      `gorm:"type:decimal(10,2);" json:"cost"`
-	Stock       int                          `gorm:"not null" json:"stock"`
-	Available   bool                         `gorm:"not null;default:false" json:"available"`
-	Images      []product_image.ProductImage `gorm:"foreignKey:ProductID" json:"images"`
-	Categories  []category.Category          `gorm:"many2many:product_categories;" json:"categories"`
-	CreatedAt   time.Time                    `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt   time.Time                    `gorm:"autoUpdateTime" json:"updated_at"`
+	ID          uint    `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name        string  `gorm:"type:varchar(100);not null" json:"name"`
+	Slug        string  `gorm:"type:varchar(150);unique;not null" json:"slug"`
+	SKU         *string `gorm:"type:varchar(64);uniqueIndex" json:"sku"`
+	Barcode     *string `gorm:"type:varchar(13);uniqueIndex" json:"barcode"` // EAN-13 or UPC-A
+	Description string  `gorm:"type:text" json:"description"`
+	Price       float64 `gorm:"type:decimal(10,2);not null" json:"price"`
+	Cost        float64 `gorm:"type:decimal(10,2);" json:"cost"`
+	Stock       int     `gorm:"not null" json:"stock"`
+	MinStock    int     `gorm:"not null;default:0" json:"min_stock"` // Reorder point, 0 disables alerts
+	Available   bool    `gorm:"not null;default:false" json:"available"`
+	// AvailableFrom and AvailableUntil schedule the availability: the
+	// scheduler switches the product on at AvailableFrom (clearing it) and off
+	// at AvailableUntil. Outside the window the product is hidden from the
+	// storefront.
+	AvailableFrom  *time.Time                       `gorm:"index" json:"available_from"`
+	AvailableUntil *time.Time                       `gorm:"index" json:"available_until"`
+	Images         []product_image.ProductImage     `gorm:"foreignKey:ProductID" json:"images"`
+	Variants       []product_variant.ProductVariant `gorm:"foreignKey:ProductID" json:"variants"`
+	Categories     []category.Category              `gorm:"many2many:product_categories;" json:"categories"`
+	CreatedAt      time.Time                        `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt      time.Time                        `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt      gorm.DeletedAt                   `gorm:"index" json:"deleted_at,omitempty"` // Set while the product is in the trash
 }
 
 type ProductRepository interface {
-	GetAll(
-		ctx context.Context,
-		page int,
-		pageSize int,
-		nameFilter string,
-		categoryIDs []uint,
-		onlyAvailable bool) ([]Product, int64, error)
-	Create(product Product) error
+	GetAll(ctx context.Context, page int, pageSize int, filter ProductFilter) ([]Product, int64, error)
+	GetAllByCursor(ctx context.Context, cursor *pagination.Cursor, pageSize int, filter ProductFilter) ([]Product, int64, pagination.Result, error)
+	GetFacets(ctx context.Context, query string, filter ProductFilter, priceBounds []float64) (*ProductFacets, error)
+	Search(ctx context.Context, query string, page int, pageSize int, filter ProductFilter) ([]SearchResult, int64, error)
+	StreamAll(ctx context.Context, filter ProductFilter, fn func(products []Product) error) error
+	Create(ctx context.Context, product *Product) error
 	FindBySlug(slug string) (*Product, error)
-	DeleteBySlug(productID uint) error
-	UpdateBySlug(slug string, updatedProduct Product) (Product, error)
-	ClearProductCategories(productID uint) error
-	UpdateProductCategories(product *Product) error
-	SwitchAvailable(product Product) error
-	UpdateProductStock(product *Product) error
+	FindSlugAlias(slug string) (string, error)
+	UniqueSlug(base string, productID uint, reserved map[string]bool) (string, error)
+	CreateMany(ctx context.Context, products []*Product, newCategories []category.Category) error
+	FindByCode(codes []string) (*Product, error)
+	CodeInUse(codes []string) (bool, error)
+	DeleteBySlug(ctx context.Context, productID uint) error
+	GetTrash(ctx context.Context, page int, pageSize int) ([]Product, int64, error)
+	Restore(ctx context.Context, slug string) (*Product, error)
+	FindTrashedBefore(ctx context.Context, cutoff time.Time) ([]Product, error)
+	Purge(ctx context.Context, productID uint) error
+	ApplyAvailabilitySchedule(ctx context.Context, now time.Time) (int, error)
+	UpdateBySlug(ctx context.Context, slug string, updatedProduct Product) (Product, error)
+	UpdateProductCategories(ctx context.Context, product *Product) error
+	SwitchAvailable(ctx context.Context, product Product) error
+	GetLowStock(ctx context.Context, page int, pageSize int) ([]Product, int64, error)
+	IncreaseStock(ctx context.Context, slug string, quantity int, movement stock_movement.StockMovement) (*Product, error)
+	DecreaseStock(ctx context.Context, slug string, quantity int, movement stock_movement.StockMovement) (*Product, error)
 }
 
 type ProductResponse struct {
-	ID          uint                         `json:"id"`
-	Name        string                       `json:"name"`
-	Slug        string                       `json:"slug"`
-	Description string                       `json:"description"`
-	Price       float64                      `json:"price"`
-	Stock       int                          `json:"stock"`
-	Images      []product_image.ProductImage `json:"images"`
-	Categories  []category.Category          `json:"categories"`
+	ID          uint                             `json:"id"`
+	Name        string                           `json:"name"`
+	Slug        string                           `json:"slug"`
+	SKU         *string                          `json:"sku"`
+	Barcode     *string                          `json:"barcode"`
+	Description string                           `json:"description"`
+	Price       float64                          `json:"price"`
+	Stock       int                              `json:"stock"`
+	Images      []product_image.ProductImage     `json:"images"`
+	Variants    []product_variant.ProductVariant `json:"variants"`
+	Categories  []category.Category              `json:"categories"`
+}
+
+// SearchResult is a product matched by a full-text search, with its relevance
+// and a highlighted excerpt of the matched text. The excerpt is HTML escaped,
+// with the matches wrapped in <mark> tags.
+type SearchResult struct {
+	Product Product
+	Rank    float64
+	Snippet string
+}
+
+type ProductSearchResponse struct {
+	ProductResponse
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+// WithinAvailabilityWindow reports whether now falls inside the scheduled
+// availability window, an unset bound being open.
+func (p *Product) WithinAvailabilityWindow(now time.Time) bool {
+	if p.AvailableFrom != nil && now.Before(*p.AvailableFrom) {
+		return false
+	}
+	if p.AvailableUntil != nil && !now.Before(*p.AvailableUntil) {
+		return false
+	}
+	return true
 }
 
 func (p *Product) ToResponse() *ProductResponse {
@@ -59,60 +149,46 @@ func (p *Product) ToResponse() *ProductResponse {
 		ID:          p.ID,
 		Name:        p.Name,
 		Slug:        p.Slug,
+		SKU:         p.SKU,
+		Barcode:     p.Barcode,
 		Description: p.Description,
 		Price:       p.Price,
 		Stock:       p.Stock,
 		Images:      p.Images,
+		Variants:    p.availableVariants(),
 		Categories:  p.Categories,
 	}
 }
 
-// func (p *Product) BeforeUpdate(tx *gorm.DB) (err error) {
-// 	var oldProduct Product
-// 	if err := tx.Unscoped().First(&oldProduct, p.ID).Error; err != nil {
-// 		return err
-// 	}
-
-// 	userID, err := utils.GetCurrentUserID(tx)
-// 	fmt.Println(userID)
-
-// 	if err != nil {
-// 		return err
-// 	}
-
-// 	oldValue := make(map[string]interface{})
-// 	newValue := make(map[string]interface{})
-
-// 	if oldProduct.Name != p.Name {
-// 		oldValue["name"] = oldProduct.Name
-// 		newValue["name"] = p.Name
-// 	}
-// 	if oldProduct.Price != p.Price {
-// 		oldValue["price"] = oldProduct.Price
-// 		newValue["price"] = p.Price
-// 	}
-// 	if oldProduct.Stock != p.Stock {
-// 		oldValue["stock"] = oldProduct.Stock
-// 		newValue["stock"] = p.Stock
-// 	}
-
-// 	audit := product_audit.ProductAudit{
-// 		ProductID:   p.ID,
-// 		UserID:      userID,
-// 		Action:      "updated",
-// 		OldValue:    utils.ToJSON(oldValue),
-// 		NewValue:    utils.ToJSON(newValue),
-// 		Description: "Product updated",
-// 	}
-
-// 	if err := tx.Create(&audit).Error; err != nil {
-// 		return err
-// 	}
-
-// 	return nil
-// }
-
-func GenerateSlug() string {
-	id := ksuid.New().String()
-	return id[:8]
+func (p *Product) availableVariants() []product_variant.ProductVariant {
+	variants := make([]product_variant.ProductVariant, 0, len(p.Variants))
+	for _, v := range p.Variants {
+		if v.Available {
+			variants = append(variants, v)
+		}
+	}
+	return variants
+}
+
+// ProductSlugAlias keeps a previous slug of a renamed product so old links
+// can be redirected to the current one.
+type ProductSlugAlias struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	ProductID uint      `gorm:"not null;index" json:"product_id"`
+	Slug      string    `gorm:"type:varchar(150);uniqueIndex;not null" json:"slug"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// GenerateSlug derives the slug from the product name, falling back to a
+// random id when the name has no usable characters.
+func GenerateSlug(name string) string {
+	slug := utils.Slugify(name)
+	if len(slug) > maxSlugBaseLength {
+		slug = strings.TrimRight(slug[:maxSlugBaseLength], "-")
+	}
+	if slug == "" {
+		id := ksuid.New().String()
+		return strings.ToLower(id[:8])
+	}
+	return slug
 }
@@ -0,0 +1,317 @@
+package product
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/reinaldo-silva/savina-stock/utils"
+	"github.com/xuri/excelize/v2"
+)
+
+type ExportFormat string
+
+const (
+	CSVExportFormat    ExportFormat = "csv"
+	XLSXExportFormat   ExportFormat = "xlsx"
+	NDJSONExportFormat ExportFormat = "ndjson"
+)
+
+var ErrInvalidExportFormat = errors.New("export format must be csv, xlsx or ndjson")
+
+var exportHeader = []string{
+	"id", "slug", "sku", "barcode", "name", "description", "price", "cost",
+	"stock", "min_stock", "available", "categories", "cover_image_url", "created_at", "updated_at",
+}
+
+type ExportRow struct {
+	ID            uint      `json:"id"`
+	Slug          string    `json:"slug"`
+	SKU           string    `json:"sku"`
+	Barcode       string    `json:"barcode"`
+	Name          string    `json:"name"`
+	Description   string    `json:"description"`
+	Price         float64   `json:"price"`
+	Cost          float64   `json:"cost"`
+	Stock         int       `json:"stock"`
+	MinStock      int       `json:"min_stock"`
+	Available     bool      `json:"available"`
+	Categories    []string  `json:"categories"`
+	CoverImageURL string    `json:"cover_image_url"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+func ParseExportFormat(value string) (ExportFormat, error) {
+	switch format := ExportFormat(strings.ToLower(value)); format {
+	case CSVExportFormat, XLSXExportFormat, NDJSONExportFormat:
+		return format, nil
+	case "":
+		return CSVExportFormat, nil
+	}
+	return "", ErrInvalidExportFormat
+}
+
+func (f ExportFormat) ContentType() string {
+	switch f {
+	case XLSXExportFormat:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case NDJSONExportFormat:
+		return "application/x-ndjson"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Export writes every product matching the filters to w. Products are read in
+// batches and written as they arrive, so the catalogue is never held in memory
+// as a whole. flush, when set, is called after each batch.
+func (uc *ProductUseCase) Export(
+	ctx context.Context,
+	w io.Writer,
+	format ExportFormat,
+	filter ProductFilter,
+	host string,
+	flush func()) error {
+
+	writer, err := newExportWriter(w, format)
+	if err != nil {
+		return err
+	}
+
+	err = uc.repo.StreamAll(ctx, filter, func(products []Product) error {
+		for _, p := range products {
+			if err := writer.Write(toExportRow(p, host)); err != nil {
+				return err
+			}
+		}
+
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+		if flush != nil {
+			flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
+
+func toExportRow(p Product, host string) ExportRow {
+	row := ExportRow{
+		ID:          p.ID,
+		Slug:        p.Slug,
+		Name:        p.Name,
+		Description: p.Description,
+		Price:       p.Price,
+		Cost:        p.Cost,
+		Stock:       p.Stock,
+		MinStock:    p.MinStock,
+		Available:   p.Available,
+		Categories:  make([]string, 0, len(p.Categories)),
+		CreatedAt:   p.CreatedAt,
+		UpdatedAt:   p.UpdatedAt,
+	}
+
+	if p.SKU != nil {
+		row.SKU = *p.SKU
+	}
+	if p.Barcode != nil {
+		row.Barcode = *p.Barcode
+	}
+
+	for _, c := range p.Categories {
+		row.Categories = append(row.Categories, c.Name)
+	}
+
+	for _, image := range p.Images {
+		if image.IsCover {
+			row.CoverImageURL = utils.GenerateImageURL(host, image.PublicID)
+			break
+		}
+	}
+
+	return row
+}
+
+func (row ExportRow) values() []string {
+	return []string{
+		strconv.FormatUint(uint64(row.ID), 10),
+		row.Slug,
+		row.SKU,
+		row.Barcode,
+		row.Name,
+		row.Description,
+		strconv.FormatFloat(row.Price, 'f', 2, 64),
+		strconv.FormatFloat(row.Cost, 'f', 2, 64),
+		strconv.Itoa(row.Stock),
+		strconv.Itoa(row.MinStock),
+		strconv.FormatBool(row.Available),
+		strings.Join(row.Categories, "|"),
+		row.CoverImageURL,
+		row.CreatedAt.Format(time.RFC3339),
+		row.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+type exportWriter interface {
+	Write(row ExportRow) error
+	Flush() error
+	Close() error
+}
+
+func newExportWriter(w io.Writer, format ExportFormat) (exportWriter, error) {
+	switch format {
+	case CSVExportFormat:
+		return newCSVExportWriter(w)
+	case XLSXExportFormat:
+		return newXLSXExportWriter(w)
+	case NDJSONExportFormat:
+		return &ndjsonExportWriter{encoder: json.NewEncoder(w)}, nil
+	}
+	return nil, ErrInvalidExportFormat
+}
+
+type csvExportWriter struct {
+	writer *csv.Writer
+}
+
+func newCSVExportWriter(w io.Writer) (*csvExportWriter, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(exportHeader); err != nil {
+		return nil, err
+	}
+	return &csvExportWriter{writer: writer}, nil
+}
+
+func (e *csvExportWriter) Write(row ExportRow) error {
+	return e.writer.Write(row.values())
+}
+
+func (e *csvExportWriter) Flush() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+func (e *csvExportWriter) Close() error {
+	return e.Flush()
+}
+
+type ndjsonExportWriter struct {
+	encoder *json.Encoder
+}
+
+func (e *ndjsonExportWriter) Write(row ExportRow) error {
+	return e.encoder.Encode(row)
+}
+
+func (e *ndjsonExportWriter) Flush() error {
+	return nil
+}
+
+func (e *ndjsonExportWriter) Close() error {
+	return nil
+}
+
+// xlsxExportWriter relies on the excelize stream writer, which spills rows to
+// a temporary file instead of keeping the whole sheet in memory. The workbook
+// can only be sent once every row is written.
+type xlsxExportWriter struct {
+	w      io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	row    int
+}
+
+func newXLSXExportWriter(w io.Writer) (*xlsxExportWriter, error) {
+	file := excelize.NewFile()
+
+	stream, err := file.NewStreamWriter(file.GetSheetName(0))
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	e := &xlsxExportWriter{w: w, file: file, stream: stream, row: 1}
+	header := make([]interface{}, len(exportHeader))
+	for i, column := range exportHeader {
+		header[i] = column
+	}
+	if err := e.writeCells(header); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func (e *xlsxExportWriter) Write(row ExportRow) error {
+	return e.writeCells([]interface{}{
+		row.ID,
+		row.Slug,
+		row.SKU,
+		row.Barcode,
+		row.Name,
+		row.Description,
+		row.Price,
+		row.Cost,
+		row.Stock,
+		row.MinStock,
+		row.Available,
+		strings.Join(row.Categories, "|"),
+		row.CoverImageURL,
+		row.CreatedAt.Format(time.RFC3339),
+		row.UpdatedAt.Format(time.RFC3339),
+	})
+}
+
+func (e *xlsxExportWriter) writeCells(cells []interface{}) error {
+	cell, err := excelize.CoordinatesToCellName(1, e.row)
+	if err != nil {
+		return err
+	}
+	e.row++
+	return e.stream.SetRow(cell, cells)
+}
+
+func (e *xlsxExportWriter) Flush() error {
+	return nil
+}
+
+func (e *xlsxExportWriter) Close() error {
+	defer e.file.Close()
+
+	if err := e.stream.Flush(); err != nil {
+		return err
+	}
+	return e.file.Write(e.w)
+}
+
+// exportResponseWriter sends the headers along with the first bytes of the
+// file. The first batch of products is read before anything is written, so
+// an export failing at the start still gets an error response.
+type exportResponseWriter struct {
+	w           http.ResponseWriter
+	contentType string
+	filename    string
+	started     bool
+}
+
+func (e *exportResponseWriter) Write(p []byte) (int, error) {
+	if !e.started {
+		e.started = true
+		e.w.Header().Set("Content-Type", e.contentType)
+		e.w.Header().Set("Content-Disposition", "attachment; filename="+e.filename)
+		e.w.WriteHeader(http.StatusOK)
+	}
+	return e.w.Write(p)
+}
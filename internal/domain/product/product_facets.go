@@ -0,0 +1,74 @@
+package product
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const maxPriceBuckets = 20
+
+// DefaultPriceBuckets are the upper bounds of the price buckets used when the
+// request does not set price_buckets. The last bucket has no upper bound.
+var DefaultPriceBuckets = []float64{50, 100, 200, 500}
+
+var ErrInvalidPriceBuckets = errors.New("price_buckets must be a comma separated list of up to 20 positive prices")
+
+// ProductFacets are the counts of the products matching a listing, grouped by
+// category, by stock availability and by price range.
+type ProductFacets struct {
+	Categories   []CategoryFacet    `json:"categories"`
+	Availability AvailabilityFacet  `json:"availability"`
+	PriceBuckets []PriceBucketFacet `json:"price_buckets"`
+}
+
+type CategoryFacet struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Slug  string `json:"slug"`
+	Count int64  `json:"count"`
+}
+
+type AvailabilityFacet struct {
+	InStock    int64 `json:"in_stock"`
+	OutOfStock int64 `json:"out_of_stock"`
+}
+
+// PriceBucketFacet counts the products with Min <= price < Max. Max is nil on
+// the last bucket.
+type PriceBucketFacet struct {
+	Min   float64  `json:"min"`
+	Max   *float64 `json:"max"`
+	Count int64    `json:"count"`
+}
+
+// ParsePriceBuckets reads the bucket upper bounds, e.g. "50,100,200". They
+// are sorted and deduplicated; an empty value gives DefaultPriceBuckets.
+func ParsePriceBuckets(value string) ([]float64, error) {
+	if strings.TrimSpace(value) == "" {
+		return DefaultPriceBuckets, nil
+	}
+
+	parts := strings.Split(value, ",")
+	if len(parts) > maxPriceBuckets {
+		return nil, ErrInvalidPriceBuckets
+	}
+
+	seen := make(map[float64]bool, len(parts))
+	bounds := make([]float64, 0, len(parts))
+	for _, part := range parts {
+		bound, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || math.IsNaN(bound) || math.IsInf(bound, 0) || bound <= 0 {
+			return nil, ErrInvalidPriceBuckets
+		}
+		if !seen[bound] {
+			seen[bound] = true
+			bounds = append(bounds, bound)
+		}
+	}
+
+	sort.Float64s(bounds)
+	return bounds, nil
+}
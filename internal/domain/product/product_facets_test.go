@@ -0,0 +1,37 @@
+package product
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParsePriceBuckets(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []float64
+		err   error
+	}{
+		{"", DefaultPriceBuckets, nil},
+		{"200, 50,100,50", []float64{50, 100, 200}, nil},
+		{"0,50", nil, ErrInvalidPriceBuckets},
+		{"-10", nil, ErrInvalidPriceBuckets},
+		{"abc", nil, ErrInvalidPriceBuckets},
+		{"NaN", nil, ErrInvalidPriceBuckets},
+		{"50,Inf", nil, ErrInvalidPriceBuckets},
+		{"+Inf", nil, ErrInvalidPriceBuckets},
+		{"1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16,17,18,19,20,21", nil, ErrInvalidPriceBuckets},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParsePriceBuckets(tt.value)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
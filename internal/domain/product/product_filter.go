@@ -0,0 +1,150 @@
+package product
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type SortField string
+
+const (
+	SortByPrice     SortField = "price"
+	SortByName      SortField = "name"
+	SortByCreatedAt SortField = "created_at"
+	SortByStock     SortField = "stock"
+)
+
+type CategoryMatch string
+
+const (
+	MatchAnyCategory   CategoryMatch = "any"
+	MatchAllCategories CategoryMatch = "all"
+)
+
+// ProductFilter narrows and orders product listings. Zero values mean no
+// restriction; an empty SortBy keeps the default stock ordering. With
+// MatchAllCategories a product must belong to every one of CategoryIDs,
+// otherwise to at least one of them.
+type ProductFilter struct {
+	Name          string
+	CategoryIDs   []uint
+	CategoryMatch CategoryMatch
+	OnlyAvailable bool
+	Available     *bool
+	MinPrice      *float64
+	MaxPrice      *float64
+	InStock       bool
+	UpdatedSince  *time.Time
+	SortBy        SortField
+	SortDesc      bool
+}
+
+func (f SortField) IsValid() bool {
+	switch f {
+	case SortByPrice, SortByName, SortByCreatedAt, SortByStock:
+		return true
+	}
+	return false
+}
+
+// ParseProductFilter reads the listing query parameters. available and
+// updated_since are only accepted on admin listings.
+func ParseProductFilter(query url.Values, admin bool) (ProductFilter, error) {
+	filter := ProductFilter{
+		Name:          query.Get("name"),
+		CategoryMatch: MatchAnyCategory,
+		OnlyAvailable: !admin,
+	}
+
+	seen := make(map[uint]bool)
+	for _, idStr := range query["category_ids"] {
+		id, err := strconv.ParseUint(idStr, 10, 32)
+		if err == nil && !seen[uint(id)] {
+			seen[uint(id)] = true
+			filter.CategoryIDs = append(filter.CategoryIDs, uint(id))
+		}
+	}
+
+	switch match := CategoryMatch(strings.ToLower(query.Get("category_match"))); match {
+	case "", MatchAnyCategory:
+	case MatchAllCategories:
+		filter.CategoryMatch = match
+	default:
+		return filter, errors.New("category_match must be any or all")
+	}
+
+	if sort := query.Get("sort"); sort != "" {
+		filter.SortBy = SortField(strings.ToLower(sort))
+		if !filter.SortBy.IsValid() {
+			return filter, errors.New("sort must be one of price, name, created_at or stock")
+		}
+	}
+
+	switch direction := strings.ToLower(query.Get("direction")); direction {
+	case "", "asc":
+	case "desc":
+		filter.SortDesc = true
+	default:
+		return filter, errors.New("direction must be asc or desc")
+	}
+
+	var err error
+	if filter.MinPrice, err = parsePriceParam(query, "min_price"); err != nil {
+		return filter, err
+	}
+	if filter.MaxPrice, err = parsePriceParam(query, "max_price"); err != nil {
+		return filter, err
+	}
+	if filter.MinPrice != nil && filter.MaxPrice != nil && *filter.MinPrice > *filter.MaxPrice {
+		return filter, errors.New("min_price cannot be greater than max_price")
+	}
+
+	if value := query.Get("in_stock"); value != "" {
+		if filter.InStock, err = strconv.ParseBool(value); err != nil {
+			return filter, errors.New("in_stock must be true or false")
+		}
+	}
+
+	if !admin {
+		return filter, nil
+	}
+
+	if value := query.Get("available"); value != "" {
+		available, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, errors.New("available must be true or false")
+		}
+		filter.Available = &available
+	}
+
+	if value := query.Get("updated_since"); value != "" {
+		updatedSince, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			updatedSince, err = time.Parse("2006-01-02", value)
+		}
+		if err != nil {
+			return filter, errors.New("updated_since must be a date (YYYY-MM-DD) or an RFC 3339 timestamp")
+		}
+		filter.UpdatedSince = &updatedSince
+	}
+
+	return filter, nil
+}
+
+func parsePriceParam(query url.Values, name string) (*float64, error) {
+	value := query.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	price, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(price) || math.IsInf(price, 0) || price < 0 {
+		return nil, fmt.Errorf("%s must be a non-negative number", name)
+	}
+	return &price, nil
+}
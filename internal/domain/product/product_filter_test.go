@@ -0,0 +1,36 @@
+package product
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestParseProductFilterRejectsInvalidPrices(t *testing.T) {
+	for _, query := range []string{
+		"min_price=-1",
+		"min_price=abc",
+		"min_price=NaN",
+		"max_price=Inf",
+		"max_price=-Inf",
+	} {
+		t.Run(query, func(t *testing.T) {
+			values, err := url.ParseQuery(query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ParseProductFilter(values, false); err == nil {
+				t.Fatal("the price was accepted")
+			}
+		})
+	}
+}
+
+func TestParseProductFilterReadsPrices(t *testing.T) {
+	filter, err := ParseProductFilter(url.Values{"min_price": {"0"}, "max_price": {"10.5"}}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filter.MinPrice == nil || *filter.MinPrice != 0 || filter.MaxPrice == nil || *filter.MaxPrice != 10.5 {
+		t.Fatalf("got min %v and max %v, want 0 and 10.5", filter.MinPrice, filter.MaxPrice)
+	}
+}
@@ -2,15 +2,23 @@ package product
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/reinaldo-silva/savina-stock/internal/domain/image_service"
 	"github.com/reinaldo-silva/savina-stock/internal/domain/product_image"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/stock_movement"
+	"github.com/reinaldo-silva/savina-stock/package/barcode"
+	"github.com/reinaldo-silva/savina-stock/package/pagination"
 	"github.com/reinaldo-silva/savina-stock/package/response/error"
 	"github.com/reinaldo-silva/savina-stock/package/response/response"
 	"github.com/reinaldo-silva/savina-stock/utils"
@@ -19,46 +27,98 @@ import (
 type ProductHandler struct {
 	useCase      *ProductUseCase
 	imageService *image_service.ImageService
+	maxPageSize  int
 }
 
-func NewProductHandler(uc *ProductUseCase, cs *image_service.ImageService) *ProductHandler {
-	return &ProductHandler{uc, cs}
+func NewProductHandler(uc *ProductUseCase, cs *image_service.ImageService, maxPageSize int) *ProductHandler {
+	return &ProductHandler{uc, cs, maxPageSize}
 }
 
 func (h *ProductHandler) GetProducts(w http.ResponseWriter, r *http.Request) {
-	pageStr := r.URL.Query().Get("page")
-	pageSizeStr := r.URL.Query().Get("pageSize")
-	nameFilter := r.URL.Query().Get("name")
-	categoryIDsStr := r.URL.Query()["category_ids"]
+	params, err := pagination.FromRequest(r, h.maxPageSize)
+	if err != nil {
+		appError := error.NewAppError(err.Error(), http.StatusBadRequest)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
 
-	page, err := strconv.Atoi(pageStr)
-	if err != nil || page < 1 {
-		page = 1
+	filter, err := ParseProductFilter(r.URL.Query(), false)
+	if err != nil {
+		appError := error.NewAppError(err.Error(), http.StatusBadRequest)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
 	}
 
-	pageSize, err := strconv.Atoi(pageSizeStr)
-	if err != nil || pageSize < 1 {
-		pageSize = 10
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+
+	// Facets are only computed on request, they cost three extra queries.
+	var facets interface{}
+	if withFacets, _ := strconv.ParseBool(r.URL.Query().Get("facets")); withFacets {
+		priceBounds, err := ParsePriceBuckets(r.URL.Query().Get("price_buckets"))
+		if err != nil {
+			appError := error.NewAppError(err.Error(), http.StatusBadRequest)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(appError.StatusCode)
+			json.NewEncoder(w).Encode(appError)
+			return
+		}
+
+		facets, err = h.useCase.GetFacets(r.Context(), query, filter, priceBounds)
+		if err != nil {
+			appError := error.NewAppError(err.Error(), http.StatusInternalServerError)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(appError.StatusCode)
+			json.NewEncoder(w).Encode(appError)
+			return
+		}
 	}
 
-	var categoryIDs []uint
-	for _, idStr := range categoryIDsStr {
-		id, err := strconv.ParseUint(idStr, 10, 32)
-		if err == nil {
-			categoryIDs = append(categoryIDs, uint(id))
+	if query != "" {
+		if params.CursorMode {
+			appError := error.NewAppError(ErrCursorWithSearch.Error(), http.StatusBadRequest)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(appError.StatusCode)
+			json.NewEncoder(w).Encode(appError)
+			return
+		}
+
+		results, total, err := h.useCase.Search(r.Context(), query, params.Page, params.PageSize, filter, r.Host)
+		if err != nil {
+			appError := error.NewAppError(err.Error(), http.StatusInternalServerError)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(appError.StatusCode)
+			json.NewEncoder(w).Encode(appError)
+			return
 		}
+
+		appResponse := response.NewAppResponse(results, "Products fetched successfully", &total).
+			WithFacets(facets)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(appResponse)
+		return
 	}
 
-	products, total, err := h.useCase.GetAll(r.Context(), page, pageSize, nameFilter, categoryIDs, r.Host)
+	products, total, result, err := h.useCase.GetAll(r.Context(), params, filter, r.Host)
 	if err != nil {
-		appError := error.NewAppError(err.Error(), http.StatusInternalServerError)
+		status := http.StatusInternalServerError
+		if errors.Is(err, pagination.ErrInvalidCursor) {
+			status = http.StatusBadRequest
+		}
+		appError := error.NewAppError(err.Error(), status)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(appError.StatusCode)
 		json.NewEncoder(w).Encode(appError)
 		return
 	}
 
-	appResponse := response.NewAppResponse(products, "Products fetched successfully", &total)
+	appResponse := response.NewAppResponse(products, "Products fetched successfully", &total).
+		WithCursors(result.NextCursor, result.PrevCursor).
+		WithFacets(facets)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -66,30 +126,57 @@ func (h *ProductHandler) GetProducts(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *ProductHandler) GetProductsToAdmin(w http.ResponseWriter, r *http.Request) {
-	pageStr := r.URL.Query().Get("page")
-	pageSizeStr := r.URL.Query().Get("pageSize")
-	nameFilter := r.URL.Query().Get("name")
-	categoryIDsStr := r.URL.Query()["category_ids"]
-
-	page, err := strconv.Atoi(pageStr)
-	if err != nil || page < 1 {
-		page = 1
+	params, err := pagination.FromRequest(r, h.maxPageSize)
+	if err != nil {
+		appError := error.NewAppError(err.Error(), http.StatusBadRequest)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
 	}
 
-	pageSize, err := strconv.Atoi(pageSizeStr)
-	if err != nil || pageSize < 1 {
-		pageSize = 10
+	filter, err := ParseProductFilter(r.URL.Query(), true)
+	if err != nil {
+		appError := error.NewAppError(err.Error(), http.StatusBadRequest)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
 	}
 
-	var categoryIDs []uint
-	for _, idStr := range categoryIDsStr {
-		id, err := strconv.ParseUint(idStr, 10, 32)
-		if err == nil {
-			categoryIDs = append(categoryIDs, uint(id))
+	products, total, result, err := h.useCase.GetAllToAdmin(r.Context(), params, filter, r.Host)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, pagination.ErrInvalidCursor) {
+			status = http.StatusBadRequest
 		}
+		appError := error.NewAppError(err.Error(), status)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	appResponse := response.NewAppResponse(products, "Products fetched successfully", &total).
+		WithCursors(result.NextCursor, result.PrevCursor)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(appResponse)
+}
+
+func (h *ProductHandler) GetLowStockProducts(w http.ResponseWriter, r *http.Request) {
+
+	params, err := pagination.FromRequest(r, h.maxPageSize)
+	if err != nil {
+		appError := error.NewAppError(err.Error(), http.StatusBadRequest)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
 	}
 
-	products, total, err := h.useCase.GetAllToAdmin(r.Context(), page, pageSize, nameFilter, categoryIDs, r.Host)
+	products, total, err := h.useCase.GetLowStock(r.Context(), params.Page, params.PageSize, r.Host)
 	if err != nil {
 		appError := error.NewAppError(err.Error(), http.StatusInternalServerError)
 		w.Header().Set("Content-Type", "application/json")
@@ -98,7 +185,7 @@ func (h *ProductHandler) GetProductsToAdmin(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	appResponse := response.NewAppResponse(products, "Products fetched successfully", &total)
+	appResponse := response.NewAppResponse(products, "Low stock products fetched successfully", &total)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -117,7 +204,7 @@ func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if newProduct.Name == "" || newProduct.Price <= 0 {
+	if newProduct.Name == "" || newProduct.Price <= 0 || newProduct.MinStock < 0 {
 		appError := error.NewAppError("Invalid product data")
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(appError.StatusCode)
@@ -125,9 +212,15 @@ func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	createdProduct, err := h.useCase.Create(newProduct)
+	createdProduct, err := h.useCase.Create(r.Context(), newProduct)
 	if err != nil {
-		appError := error.NewAppError(err.Error(), http.StatusInternalServerError)
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, barcode.ErrInvalidBarcode) || errors.Is(err, ErrInvalidProduct) {
+			statusCode = http.StatusBadRequest
+		} else if errors.Is(err, ErrDuplicateCode) || errors.Is(err, ErrDuplicateSlug) {
+			statusCode = http.StatusConflict
+		}
+		appError := error.NewAppError(err.Error(), statusCode)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(appError.StatusCode)
 		json.NewEncoder(w).Encode(appError)
@@ -145,6 +238,15 @@ func (h *ProductHandler) GetProductBySlug(w http.ResponseWriter, r *http.Request
 
 	product, err := h.useCase.GetBySlug(slug)
 	if err != nil {
+		if currentSlug, aliasErr := h.useCase.ResolveSlugAlias(slug); aliasErr == nil {
+			location := strings.TrimSuffix(r.URL.Path, slug) + currentSlug
+			if r.URL.RawQuery != "" {
+				location += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, location, http.StatusMovedPermanently)
+			return
+		}
+
 		appError := error.NewAppError("Product not found", http.StatusNotFound)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(appError.StatusCode)
@@ -188,21 +290,191 @@ func (h *ProductHandler) GetProductBySlugToAdmin(w http.ResponseWriter, r *http.
 	json.NewEncoder(w).Encode(appResponse)
 }
 
-func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
+func (h *ProductHandler) ImportProducts(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		appError := error.NewAppError("Invalid multipart form", http.StatusBadRequest)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	file, fileHeader, err := r.FormFile("file")
+	if err != nil {
+		appError := error.NewAppError("File is required", http.StatusBadRequest)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+	defer file.Close()
+
+	format := ImportFormat(strings.ToLower(strings.TrimPrefix(filepath.Ext(fileHeader.Filename), ".")))
+
+	rows, err := ParseImportFile(file, format)
+	if err != nil {
+		appError := error.NewAppError(err.Error(), http.StatusBadRequest)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	opts := ImportOptions{
+		DryRun:           r.URL.Query().Get("dry_run") == "true",
+		CreateCategories: r.URL.Query().Get("create_categories") == "true",
+	}
+
+	report, err := h.useCase.Import(r.Context(), rows, opts)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, ErrDuplicateCode) || errors.Is(err, ErrDuplicateSlug) {
+			statusCode = http.StatusConflict
+		}
+		appError := error.NewAppError(err.Error(), statusCode)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	message := "Products imported successfully"
+	if opts.DryRun {
+		message = "Import validated successfully"
+	}
+
+	appResponse := response.NewAppResponse(report, message, nil)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(appResponse.StatusCode)
+	json.NewEncoder(w).Encode(appResponse)
+}
+
+func (h *ProductHandler) ExportProducts(w http.ResponseWriter, r *http.Request) {
+	format, err := ParseExportFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		appError := error.NewAppError(err.Error(), http.StatusBadRequest)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	filter, err := ParseProductFilter(r.URL.Query(), true)
+	if err != nil {
+		appError := error.NewAppError(err.Error(), http.StatusBadRequest)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	// A full catalogue export can outlive the server write timeout.
+	controller := http.NewResponseController(w)
+	controller.SetWriteDeadline(time.Time{})
+
+	out := &exportResponseWriter{
+		w:           w,
+		contentType: format.ContentType(),
+		filename:    fmt.Sprintf("produtos-%s.%s", time.Now().Format("20060102"), format),
+	}
+
+	err = h.useCase.Export(r.Context(), out, format, filter, r.Host, func() {
+		if out.started {
+			controller.Flush()
+		}
+	})
+	if err != nil {
+		log.Printf("failed to export products: %v", err)
+
+		// Once the file has started the status can no longer change, so the
+		// connection is dropped for the client to see a failed download
+		// rather than a truncated file.
+		if out.started {
+			panic(http.ErrAbortHandler)
+		}
+
+		appError := error.NewAppError("Failed to export products", http.StatusInternalServerError)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+	}
+}
+
+func (h *ProductHandler) GetProductByCode(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
 
+	product, err := h.useCase.GetByCode(code)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, ErrProductNotFound) {
+			statusCode = http.StatusNotFound
+		}
+		appError := error.NewAppError(err.Error(), statusCode)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	host := r.Host
+
+	for i := range product.Images {
+		product.Images[i].ImageURL = utils.GenerateImageURL(host, product.Images[i].PublicID)
+	}
+
+	appResponse := response.NewAppResponse(product, "Product fetched successfully", nil)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(appResponse.StatusCode)
+	json.NewEncoder(w).Encode(appResponse)
+}
+
+func (h *ProductHandler) GetProductBarcode(w http.ResponseWriter, r *http.Request) {
 	slug := chi.URLParam(r, "slug")
 
-	err := h.useCase.Delete(slug)
+	format := barcode.Format(strings.ToLower(r.URL.Query().Get("format")))
+	if format == "" {
+		format = barcode.SVGFormat
+	}
+
+	image, contentType, err := h.useCase.GetBarcodeImage(slug, format)
 	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, ErrProductNotFound) || errors.Is(err, ErrProductHasNoCode) {
+			statusCode = http.StatusNotFound
+		} else if errors.Is(err, barcode.ErrInvalidFormat) {
+			statusCode = http.StatusBadRequest
+		}
+		appError := error.NewAppError(err.Error(), statusCode)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
 
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", "inline; filename="+slug+"."+string(format))
+	w.WriteHeader(http.StatusOK)
+	w.Write(image)
+}
+
+func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
+
+	slug := chi.URLParam(r, "slug")
+
+	err := h.useCase.Delete(r.Context(), slug)
+	if err != nil {
 		appError := error.NewAppError("Product not found", http.StatusNotFound)
+		if !errors.Is(err, ErrProductNotFound) {
+			log.Printf("failed to delete product %s: %v", slug, err)
+			appError = error.NewAppError("Failed to delete product", http.StatusInternalServerError)
+		}
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(appError.StatusCode)
 		json.NewEncoder(w).Encode(appError)
 		return
 	}
 
-	appResponse := response.NewAppResponse(nil, "Product deleted successfully", nil)
+	appResponse := response.NewAppResponse(nil, "Product moved to trash successfully", nil)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(appResponse.StatusCode)
 	json.NewEncoder(w).Encode(appResponse)
@@ -222,7 +494,7 @@ func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if updatedProduct.Name == "" || updatedProduct.Price <= 0 {
+	if updatedProduct.Name == "" || updatedProduct.Price <= 0 || updatedProduct.MinStock < 0 {
 		appError := error.NewAppError("Invalid product data", http.StatusBadRequest)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(appError.StatusCode)
@@ -230,9 +502,17 @@ func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	updatedProductRes, err := h.useCase.Update(slug, updatedProduct)
+	regenerateSlug := r.URL.Query().Get("regenerate_slug") == "true"
+
+	updatedProductRes, err := h.useCase.Update(r.Context(), slug, updatedProduct, regenerateSlug)
 	if err != nil {
-		appError := error.NewAppError(err.Error(), http.StatusInternalServerError)
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, barcode.ErrInvalidBarcode) || errors.Is(err, ErrInvalidProduct) {
+			statusCode = http.StatusBadRequest
+		} else if errors.Is(err, ErrDuplicateCode) || errors.Is(err, ErrDuplicateSlug) {
+			statusCode = http.StatusConflict
+		}
+		appError := error.NewAppError(err.Error(), statusCode)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(appError.StatusCode)
 		json.NewEncoder(w).Encode(appError)
@@ -311,7 +591,7 @@ func (h *ProductHandler) UploadImages(w http.ResponseWriter, r *http.Request) {
 		os.Remove(tempFile.Name())
 	}
 
-	err := h.useCase.AddImagesToProduct(slug, uploadedImages)
+	err := h.useCase.AddImagesToProduct(r.Context(), slug, uploadedImages)
 	if err != nil {
 		appError := error.NewAppError(err.Error(), http.StatusInternalServerError)
 		w.Header().Set("Content-Type", "application/json")
@@ -364,7 +644,7 @@ func (h *ProductHandler) LinkCategories(w http.ResponseWriter, r *http.Request)
 	slug := chi.URLParam(r, "slug")
 
 	if categories == "" {
-		err := h.useCase.UpdateProductCategories(slug, []int{})
+		err := h.useCase.UpdateProductCategories(r.Context(), slug, []int{})
 		if err != nil {
 			appError := error.NewAppError(err.Error())
 			w.Header().Set("Content-Type", "application/json")
@@ -400,7 +680,7 @@ func (h *ProductHandler) LinkCategories(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	err := h.useCase.UpdateProductCategories(slug, intArray)
+	err := h.useCase.UpdateProductCategories(r.Context(), slug, intArray)
 	if err != nil {
 		appError := error.NewAppError(err.Error())
 		w.Header().Set("Content-Type", "application/json")
@@ -418,7 +698,7 @@ func (h *ProductHandler) LinkCategories(w http.ResponseWriter, r *http.Request)
 func (h *ProductHandler) SwitchAvailable(w http.ResponseWriter, r *http.Request) {
 	slug := chi.URLParam(r, "slug")
 
-	err := h.useCase.SwitchAvailable(slug)
+	err := h.useCase.SwitchAvailable(r.Context(), slug)
 	if err != nil {
 		appError := error.NewAppError(err.Error(), http.StatusInternalServerError)
 		w.Header().Set("Content-Type", "application/json")
@@ -437,7 +717,9 @@ func (h *ProductHandler) ProductStockEntry(w http.ResponseWriter, r *http.Reques
 	slug := chi.URLParam(r, "slug")
 
 	var body struct {
-		Quantity int `json:"quantity"`
+		Quantity  int    `json:"quantity"`
+		Reason    string `json:"reason"`
+		Reference string `json:"reference"`
 	}
 
 	err := json.NewDecoder(r.Body).Decode(&body)
@@ -449,9 +731,17 @@ func (h *ProductHandler) ProductStockEntry(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	err = h.useCase.ProductStockEntry(slug, body.Quantity)
+	err = h.useCase.ProductStockEntry(r.Context(), slug, body.Quantity, body.Reason, body.Reference)
 	if err != nil {
-		appError := error.NewAppError(err.Error(), http.StatusInternalServerError)
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, ErrInvalidQuantity) {
+			statusCode = http.StatusBadRequest
+		} else if errors.Is(err, ErrProductNotFound) {
+			statusCode = http.StatusNotFound
+		} else if errors.Is(err, ErrInsufficientStock) || errors.Is(err, ErrProductHasVariants) {
+			statusCode = http.StatusConflict
+		}
+		appError := error.NewAppError(err.Error(), statusCode)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(appError.StatusCode)
 		json.NewEncoder(w).Encode(appError)
@@ -468,7 +758,9 @@ func (h *ProductHandler) ProductStockOut(w http.ResponseWriter, r *http.Request)
 	slug := chi.URLParam(r, "slug")
 
 	var body struct {
-		Quantity int `json:"quantity"`
+		Quantity  int    `json:"quantity"`
+		Reason    string `json:"reason"`
+		Reference string `json:"reference"`
 	}
 
 	err := json.NewDecoder(r.Body).Decode(&body)
@@ -480,9 +772,17 @@ func (h *ProductHandler) ProductStockOut(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = h.useCase.ProductStockOut(slug, body.Quantity)
+	err = h.useCase.ProductStockOut(r.Context(), slug, body.Quantity, body.Reason, body.Reference)
 	if err != nil {
-		appError := error.NewAppError(err.Error(), http.StatusInternalServerError)
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, ErrInvalidQuantity) {
+			statusCode = http.StatusBadRequest
+		} else if errors.Is(err, ErrProductNotFound) {
+			statusCode = http.StatusNotFound
+		} else if errors.Is(err, ErrInsufficientStock) || errors.Is(err, ErrProductHasVariants) {
+			statusCode = http.StatusConflict
+		}
+		appError := error.NewAppError(err.Error(), statusCode)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(appError.StatusCode)
 		json.NewEncoder(w).Encode(appError)
@@ -494,3 +794,156 @@ func (h *ProductHandler) ProductStockOut(w http.ResponseWriter, r *http.Request)
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(appResponse)
 }
+
+func (h *ProductHandler) GetStockMovements(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "slug")
+	typesStr := r.URL.Query()["type"]
+	startDateStr := r.URL.Query().Get("start_date")
+	endDateStr := r.URL.Query().Get("end_date")
+
+	params, err := pagination.FromRequest(r, h.maxPageSize)
+	if err != nil {
+		appError := error.NewAppError(err.Error(), http.StatusBadRequest)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	var movementTypes []stock_movement.MovementType
+	for _, typeStr := range typesStr {
+		for _, t := range strings.Split(typeStr, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				movementTypes = append(movementTypes, stock_movement.MovementType(strings.ToUpper(t)))
+			}
+		}
+	}
+
+	var startDate, endDate *time.Time
+
+	if startDateStr != "" {
+		parsed, err := time.Parse("2006-01-02", startDateStr)
+		if err != nil {
+			appError := error.NewAppError("Invalid start_date, expected format YYYY-MM-DD", http.StatusBadRequest)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(appError.StatusCode)
+			json.NewEncoder(w).Encode(appError)
+			return
+		}
+		startDate = &parsed
+	}
+
+	if endDateStr != "" {
+		parsed, err := time.Parse("2006-01-02", endDateStr)
+		if err != nil {
+			appError := error.NewAppError("Invalid end_date, expected format YYYY-MM-DD", http.StatusBadRequest)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(appError.StatusCode)
+			json.NewEncoder(w).Encode(appError)
+			return
+		}
+		endDate = &parsed
+	}
+
+	movements, total, err := h.useCase.GetStockMovements(r.Context(), slug, params.Page, params.PageSize, movementTypes, startDate, endDate)
+	if err != nil {
+		var appError error.AppError
+		switch {
+		case errors.Is(err, ErrInvalidMovementFilter):
+			appError = error.NewAppError(err.Error(), http.StatusBadRequest)
+		case errors.Is(err, ErrProductNotFound):
+			appError = error.NewAppError("Product not found", http.StatusNotFound)
+		default:
+			log.Printf("failed to fetch stock movements: %v", err)
+			appError = error.NewAppError("Failed to fetch stock movements", http.StatusInternalServerError)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	appResponse := response.NewAppResponse(movements, "Stock movements fetched successfully", &total)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(appResponse)
+}
+
+func (h *ProductHandler) GetProductAudit(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "slug")
+
+	params, err := pagination.FromRequest(r, h.maxPageSize)
+	if err != nil {
+		appError := error.NewAppError(err.Error(), http.StatusBadRequest)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	audits, total, err := h.useCase.GetAudit(r.Context(), slug, params.Page, params.PageSize)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, ErrProductNotFound) {
+			statusCode = http.StatusNotFound
+		}
+		appError := error.NewAppError(err.Error(), statusCode)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	appResponse := response.NewAppResponse(audits, "Product audit fetched successfully", &total)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(appResponse)
+}
+
+func (h *ProductHandler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	params, err := pagination.FromRequest(r, h.maxPageSize)
+	if err != nil {
+		appError := error.NewAppError(err.Error(), http.StatusBadRequest)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	products, total, err := h.useCase.GetTrash(r.Context(), params.Page, params.PageSize, r.Host)
+	if err != nil {
+		appError := error.NewAppError(err.Error(), http.StatusInternalServerError)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	appResponse := response.NewAppResponse(products, "Trashed products fetched successfully", &total)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(appResponse)
+}
+
+func (h *ProductHandler) RestoreProduct(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "slug")
+
+	product, err := h.useCase.Restore(r.Context(), slug, r.Host)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrProductNotInTrash) {
+			status = http.StatusNotFound
+		}
+		appError := error.NewAppError(err.Error(), status)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	appResponse := response.NewAppResponse(product, "Product restored successfully", nil)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(appResponse.StatusCode)
+	json.NewEncoder(w).Encode(appResponse)
+}
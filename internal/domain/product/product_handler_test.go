@@ -0,0 +1,203 @@
+package product
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/stock_movement"
+)
+
+// streamRepository serves pages to StreamAll and fails with err once they
+// run out. The other methods are not used by the export.
+type streamRepository struct {
+	ProductRepository
+	pages [][]Product
+	err   error
+}
+
+func (r *streamRepository) StreamAll(ctx context.Context, filter ProductFilter, fn func(products []Product) error) error {
+	for _, page := range r.pages {
+		if err := fn(page); err != nil {
+			return err
+		}
+	}
+	return r.err
+}
+
+func exportProducts(repo *streamRepository) *httptest.ResponseRecorder {
+	h := NewProductHandler(NewProductUseCase(repo, nil, nil, nil, nil, nil, nil), nil, 50)
+	r := httptest.NewRequest(http.MethodGet, "/products/export?format=csv", nil)
+	w := httptest.NewRecorder()
+	h.ExportProducts(w, r)
+	return w
+}
+
+func TestExportProductsStreamsACSV(t *testing.T) {
+	w := exportProducts(&streamRepository{pages: [][]Product{{{ID: 1, Slug: "bolsa", Name: "Bolsa"}}}})
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+	if !strings.HasPrefix(w.Header().Get("Content-Disposition"), "attachment;") {
+		t.Fatalf("got Content-Disposition %q, want an attachment", w.Header().Get("Content-Disposition"))
+	}
+	if !strings.Contains(w.Body.String(), "bolsa") {
+		t.Fatalf("the product is missing from the file: %s", w.Body)
+	}
+}
+
+func TestExportProductsFailingBeforeTheFirstPageReturnsAnError(t *testing.T) {
+	w := exportProducts(&streamRepository{err: errors.New("connection refused")})
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("got status %d, want 500", w.Code)
+	}
+	if w.Header().Get("Content-Disposition") != "" {
+		t.Fatal("the error was sent as a file download")
+	}
+}
+
+func TestExportProductsFailingMidStreamAbortsTheResponse(t *testing.T) {
+	repo := &streamRepository{
+		pages: [][]Product{{{ID: 1, Slug: "bolsa", Name: "Bolsa"}}},
+		err:   errors.New("connection reset"),
+	}
+
+	defer func() {
+		if recovered := recover(); recovered != http.ErrAbortHandler {
+			t.Fatalf("got panic %v, want http.ErrAbortHandler", recovered)
+		}
+	}()
+	exportProducts(repo)
+}
+
+func TestUpdateProductRejectsInvalidData(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"negative stock", `{"name":"Bolsa","price":10,"stock":-1}`},
+		{"negative cost", `{"name":"Bolsa","price":10,"cost":-1}`},
+		{"schedule ending before it starts", `{"name":"Bolsa","price":10,"available_from":"2026-05-02T00:00:00Z","available_until":"2026-05-01T00:00:00Z"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The embedded repository is nil, so reaching it would panic.
+			h := NewProductHandler(NewProductUseCase(&streamRepository{}, nil, nil, nil, nil, nil, nil), nil, 50)
+			r := httptest.NewRequest(http.MethodPut, "/products/bolsa", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			h.UpdateProduct(w, r)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("got status %d, want 400: %s", w.Code, w.Body)
+			}
+		})
+	}
+}
+
+// slugRepository finds the products in products by slug. The other methods
+// are not used by the handlers under test.
+type slugRepository struct {
+	ProductRepository
+	products  map[string]*Product
+	err       error
+	deleteErr error
+}
+
+func (r *slugRepository) DeleteBySlug(ctx context.Context, productID uint) error {
+	return r.deleteErr
+}
+
+func (r *slugRepository) FindBySlug(slug string) (*Product, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if p, ok := r.products[slug]; ok {
+		return p, nil
+	}
+	return nil, errors.New("record not found")
+}
+
+// movementRepository fails every listing with err.
+type movementRepository struct {
+	err error
+}
+
+func (r movementRepository) GetByProductID(
+	ctx context.Context,
+	productID uint,
+	page int,
+	pageSize int,
+	movementTypes []stock_movement.MovementType,
+	startDate *time.Time,
+	endDate *time.Time) ([]stock_movement.StockMovement, int64, error) {
+	return nil, 0, r.err
+}
+
+func TestGetStockMovementsStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		err    error
+		status int
+	}{
+		{"unknown product", "/products/sapato/stock-movements", nil, http.StatusNotFound},
+		{"invalid type", "/products/bolsa/stock-movements?type=GIFT", nil, http.StatusBadRequest},
+		{"end before start", "/products/bolsa/stock-movements?start_date=2026-05-02&end_date=2026-05-01", nil, http.StatusBadRequest},
+		{"database error", "/products/bolsa/stock-movements", errors.New("connection refused"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &slugRepository{products: map[string]*Product{"bolsa": {ID: 1, Slug: "bolsa"}}}
+			h := NewProductHandler(NewProductUseCase(repo, nil, nil, movementRepository{err: tt.err}, nil, nil, nil), nil, 50)
+			router := chi.NewRouter()
+			router.Get("/products/{slug}/stock-movements", h.GetStockMovements)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.status {
+				t.Fatalf("got status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if strings.Contains(w.Body.String(), "connection refused") {
+				t.Fatal("the database error leaked into the response")
+			}
+		})
+	}
+}
+
+func TestDeleteProductStatus(t *testing.T) {
+	tests := []struct {
+		name      string
+		slug      string
+		deleteErr error
+		status    int
+	}{
+		{"moved to trash", "bolsa", nil, http.StatusOK},
+		{"unknown product", "sapato", nil, http.StatusNotFound},
+		{"deleted meanwhile", "bolsa", ErrProductNotFound, http.StatusNotFound},
+		{"database error", "bolsa", errors.New("connection refused"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &slugRepository{products: map[string]*Product{"bolsa": {ID: 1, Slug: "bolsa"}}, deleteErr: tt.deleteErr}
+			h := NewProductHandler(NewProductUseCase(repo, nil, nil, nil, nil, nil, nil), nil, 50)
+			router := chi.NewRouter()
+			router.Delete("/products/{slug}", h.DeleteProduct)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/products/"+tt.slug, nil))
+			if w.Code != tt.status {
+				t.Fatalf("got status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+		})
+	}
+}
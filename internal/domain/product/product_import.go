@@ -0,0 +1,370 @@
+package product
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/reinaldo-silva/savina-stock/internal/domain/category"
+	"github.com/reinaldo-silva/savina-stock/package/barcode"
+	"github.com/xuri/excelize/v2"
+)
+
+type ImportFormat string
+
+const (
+	CSVImportFormat  ImportFormat = "csv"
+	XLSXImportFormat ImportFormat = "xlsx"
+)
+
+var (
+	ErrInvalidImportFile = errors.New("import file must be a CSV or XLSX spreadsheet")
+	ErrMissingNameColumn = errors.New("import file must have a name column")
+	ErrEmptyImportFile   = errors.New("import file has no product rows")
+	ErrTooManyImportRows = fmt.Errorf("import file cannot have more than %d rows", maxImportRows)
+)
+
+const maxImportRows = 5000
+
+// Portuguese headers accepted besides the English column names.
+var importColumnAliases = map[string]string{
+	"nome":       "name",
+	"descricao":  "description",
+	"preco":      "price",
+	"custo":      "cost",
+	"estoque":    "stock",
+	"category":   "categories",
+	"categoria":  "categories",
+	"categorias": "categories",
+}
+
+// ImportRow holds the raw cell values of one spreadsheet line. Line is the
+// spreadsheet line number, counting the header as line 1.
+type ImportRow struct {
+	Line        int
+	Name        string
+	Description string
+	Price       string
+	Cost        string
+	Stock       string
+	Categories  string
+	SKU         string
+}
+
+type ImportOptions struct {
+	DryRun           bool
+	CreateCategories bool
+}
+
+type ImportRowError struct {
+	Line    int    `json:"line"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+type ImportReport struct {
+	DryRun            bool             `json:"dry_run"`
+	TotalRows         int              `json:"total_rows"`
+	ValidRows         int              `json:"valid_rows"`
+	ImportedRows      int              `json:"imported_rows"`
+	CreatedCategories []string         `json:"created_categories"`
+	Errors            []ImportRowError `json:"errors"`
+}
+
+// ParseImportFile reads the product rows of a CSV or XLSX file. Columns are
+// matched by header name, so their order does not matter.
+func ParseImportFile(r io.Reader, format ImportFormat) ([]ImportRow, error) {
+	var records [][]string
+	var err error
+
+	switch format {
+	case CSVImportFormat:
+		records, err = readCSV(r)
+	case XLSXImportFormat:
+		records, err = readXLSX(r)
+	default:
+		return nil, ErrInvalidImportFile
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) < 2 {
+		return nil, ErrEmptyImportFile
+	}
+	if len(records)-1 > maxImportRows {
+		return nil, ErrTooManyImportRows
+	}
+
+	columns := make(map[string]int)
+	for i, header := range records[0] {
+		name := GenerateSlug(header)
+		if alias, ok := importColumnAliases[name]; ok {
+			name = alias
+		}
+		if _, exists := columns[name]; !exists {
+			columns[name] = i
+		}
+	}
+
+	if _, ok := columns["name"]; !ok {
+		return nil, ErrMissingNameColumn
+	}
+
+	cell := func(record []string, column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rows []ImportRow
+	for i, record := range records[1:] {
+		row := ImportRow{
+			Line:        i + 2,
+			Name:        cell(record, "name"),
+			Description: cell(record, "description"),
+			Price:       cell(record, "price"),
+			Cost:        cell(record, "cost"),
+			Stock:       cell(record, "stock"),
+			Categories:  cell(record, "categories"),
+			SKU:         cell(record, "sku"),
+		}
+
+		if row == (ImportRow{Line: row.Line}) {
+			continue
+		}
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return nil, ErrEmptyImportFile
+	}
+
+	return rows, nil
+}
+
+// readCSV accepts both comma and semicolon separated files, the latter being
+// what spreadsheet tools export with Brazilian locale settings.
+func readCSV(r io.Reader) ([][]string, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	content = []byte(strings.TrimPrefix(string(content), "\ufeff"))
+
+	header, _, _ := strings.Cut(string(content), "\n")
+
+	reader := csv.NewReader(strings.NewReader(string(content)))
+	reader.FieldsPerRecord = -1
+	if strings.Count(header, ";") > strings.Count(header, ",") {
+		reader.Comma = ';'
+	}
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+	}
+	return records, nil
+}
+
+func readXLSX(r io.Reader) ([][]string, error) {
+	file, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+	}
+	defer file.Close()
+
+	records, err := file.GetRows(file.GetSheetName(0))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+	}
+	return records, nil
+}
+
+// Import validates every row with the same rules as Create. Valid rows are
+// saved in a single transaction unless the import is a dry run; invalid rows
+// are only reported.
+func (uc *ProductUseCase) Import(ctx context.Context, rows []ImportRow, opts ImportOptions) (*ImportReport, error) {
+	report := &ImportReport{
+		DryRun:            opts.DryRun,
+		TotalRows:         len(rows),
+		CreatedCategories: []string{},
+		Errors:            []ImportRowError{},
+	}
+
+	existingCategories, err := uc.categoryRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	categoriesByName := make(map[string]category.Category, len(existingCategories))
+	for _, c := range existingCategories {
+		categoriesByName[strings.ToLower(c.Name)] = c
+	}
+
+	newCategories := make(map[string]bool)
+	var missingCategories []category.Category
+	reservedSlugs := make(map[string]bool, len(ReservedSlugs))
+	for slug := range ReservedSlugs {
+		reservedSlugs[slug] = true
+	}
+	reservedSKUs := make(map[string]int)
+	var products []*Product
+
+	for _, row := range rows {
+		p, rowErrors := parseImportRow(row)
+
+		if p != nil {
+			p.Categories = nil
+			for _, name := range strings.FieldsFunc(row.Categories, isCategorySeparator) {
+				name = strings.TrimSpace(name)
+				if name == "" {
+					continue
+				}
+
+				key := strings.ToLower(name)
+				if c, ok := categoriesByName[key]; ok {
+					p.Categories = append(p.Categories, c)
+					continue
+				}
+
+				if !opts.CreateCategories {
+					rowErrors = append(rowErrors, ImportRowError{Line: row.Line, Field: "categories", Message: fmt.Sprintf("category %s does not exist", name)})
+					continue
+				}
+
+				if !newCategories[key] {
+					newCategories[key] = true
+					missingCategories = append(missingCategories, category.Category{Name: name})
+				}
+				p.Categories = append(p.Categories, category.Category{Name: name})
+			}
+
+			if err := validateProduct(p); err != nil {
+				rowErrors = append(rowErrors, ImportRowError{Line: row.Line, Message: err.Error()})
+			}
+		}
+
+		if p != nil && p.SKU != nil {
+			if line, duplicated := reservedSKUs[*p.SKU]; duplicated {
+				rowErrors = append(rowErrors, ImportRowError{Line: row.Line, Field: "sku", Message: fmt.Sprintf("sku %s is repeated on line %d", *p.SKU, line)})
+			} else if inUse, err := uc.repo.CodeInUse(barcode.Equivalents(*p.SKU)); err != nil {
+				return nil, err
+			} else if inUse {
+				rowErrors = append(rowErrors, ImportRowError{Line: row.Line, Field: "sku", Message: ErrDuplicateCode.Error()})
+			}
+		}
+
+		if len(rowErrors) > 0 {
+			report.Errors = append(report.Errors, rowErrors...)
+			continue
+		}
+
+		if p.SKU != nil {
+			reservedSKUs[*p.SKU] = row.Line
+		}
+
+		slug, err := uc.repo.UniqueSlug(GenerateSlug(p.Name), 0, reservedSlugs)
+		if err != nil {
+			return nil, err
+		}
+		p.Slug = slug
+		reservedSlugs[slug] = true
+
+		products = append(products, p)
+	}
+
+	report.ValidRows = len(products)
+
+	var usedCategories []category.Category
+	for _, c := range missingCategories {
+		if isCategoryUsed(products, c.Name) {
+			usedCategories = append(usedCategories, c)
+			report.CreatedCategories = append(report.CreatedCategories, c.Name)
+		}
+	}
+
+	if opts.DryRun || len(products) == 0 {
+		return report, nil
+	}
+
+	if err := uc.repo.CreateMany(ctx, products, usedCategories); err != nil {
+		return nil, err
+	}
+	report.ImportedRows = len(products)
+
+	return report, nil
+}
+
+func parseImportRow(row ImportRow) (*Product, []ImportRowError) {
+	var rowErrors []ImportRowError
+
+	p := &Product{
+		Name:        row.Name,
+		Description: row.Description,
+	}
+
+	price, err := parseDecimal(row.Price)
+	if err != nil {
+		rowErrors = append(rowErrors, ImportRowError{Line: row.Line, Field: "price", Message: "price must be a number"})
+	}
+	p.Price = price
+
+	if row.Cost != "" {
+		cost, err := parseDecimal(row.Cost)
+		if err != nil {
+			rowErrors = append(rowErrors, ImportRowError{Line: row.Line, Field: "cost", Message: "cost must be a number"})
+		}
+		p.Cost = cost
+	}
+
+	if row.Stock != "" {
+		stock, err := strconv.Atoi(row.Stock)
+		if err != nil {
+			rowErrors = append(rowErrors, ImportRowError{Line: row.Line, Field: "stock", Message: "stock must be an integer"})
+		}
+		p.Stock = stock
+	}
+
+	if row.SKU != "" {
+		sku := row.SKU
+		p.SKU = &sku
+	}
+
+	if len(rowErrors) > 0 {
+		return nil, rowErrors
+	}
+	return p, nil
+}
+
+// parseDecimal accepts both "1234.56" and the Brazilian "1.234,56" notation.
+func parseDecimal(value string) (float64, error) {
+	value = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(value), "R$"))
+	if strings.Contains(value, ",") {
+		value = strings.ReplaceAll(value, ".", "")
+		value = strings.ReplaceAll(value, ",", ".")
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// Category names in a cell are separated by ";" or "|".
+func isCategorySeparator(r rune) bool {
+	return r == ';' || r == '|'
+}
+
+func isCategoryUsed(products []*Product, name string) bool {
+	for _, p := range products {
+		for _, c := range p.Categories {
+			if c.ID == 0 && strings.EqualFold(c.Name, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
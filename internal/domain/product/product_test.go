@@ -0,0 +1,42 @@
+package product
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestGenerateSlug(t *testing.T) {
+	// The cut at maxSlugBaseLength lands right after a word, on a hyphen.
+	wordsToTheCut := strings.Repeat("a", maxSlugBaseLength-1) + " b"
+
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"accents", "Bolsa de Couro Açaí", "bolsa-de-couro-acai"},
+		{"separator runs", "  Bolsa -- Azul!  ", "bolsa-azul"},
+		{"at the limit", strings.Repeat("a", maxSlugBaseLength), strings.Repeat("a", maxSlugBaseLength)},
+		{"over the limit", strings.Repeat("a", maxSlugBaseLength+10), strings.Repeat("a", maxSlugBaseLength)},
+		{"cut on a hyphen", wordsToTheCut, strings.Repeat("a", maxSlugBaseLength-1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GenerateSlug(tt.value); got != tt.want {
+				t.Fatalf("GenerateSlug(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateSlugFallsBackToARandomID(t *testing.T) {
+	randomID := regexp.MustCompile(`^[0-9a-z]{8}$`)
+
+	for _, name := range []string{"", "!!!", "--- ---"} {
+		if slug := GenerateSlug(name); !randomID.MatchString(slug) {
+			t.Fatalf("GenerateSlug(%q) = %q, want 8 characters of a ksuid", name, slug)
+		}
+	}
+}
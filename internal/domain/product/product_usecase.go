@@ -4,11 +4,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
 	"strings"
+	"time"
 
 	"github.com/reinaldo-silva/savina-stock/internal/domain/category"
 	"github.com/reinaldo-silva/savina-stock/internal/domain/image_service"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/product_audit"
 	"github.com/reinaldo-silva/savina-stock/internal/domain/product_image"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/stock_alert"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/stock_movement"
+	"github.com/reinaldo-silva/savina-stock/package/barcode"
+	"github.com/reinaldo-silva/savina-stock/package/pagination"
 	"github.com/reinaldo-silva/savina-stock/utils"
 )
 
@@ -16,31 +23,39 @@ type ProductUseCase struct {
 	repo         ProductRepository
 	categoryRepo category.CategoryRepository
 	imageRepo    product_image.ImageRepository
+	movementRepo stock_movement.StockMovementRepository
+	auditRepo    product_audit.ProductAuditRepository
 	imageService *image_service.ImageService
+	alertService *stock_alert.StockAlertService
 }
 
 func NewProductUseCase(
 	repo ProductRepository,
 	categoryRepo category.CategoryRepository,
 	imageRepo product_image.ImageRepository,
-	imageService *image_service.ImageService) *ProductUseCase {
+	movementRepo stock_movement.StockMovementRepository,
+	auditRepo product_audit.ProductAuditRepository,
+	imageService *image_service.ImageService,
+	alertService *stock_alert.StockAlertService) *ProductUseCase {
 	return &ProductUseCase{
 		repo:         repo,
 		categoryRepo: categoryRepo,
 		imageRepo:    imageRepo,
-		imageService: imageService}
+		movementRepo: movementRepo,
+		auditRepo:    auditRepo,
+		imageService: imageService,
+		alertService: alertService}
 }
 
 func (uc *ProductUseCase) GetAll(
 	ctx context.Context,
-	page int,
-	pageSize int,
-	nameFilter string,
-	categoryIDs []uint,
-	host string) ([]ProductResponse, int64, error) {
-	products, total, err := uc.repo.GetAll(ctx, page, pageSize, nameFilter, categoryIDs, true)
+	params pagination.Params,
+	filter ProductFilter,
+	host string) ([]ProductResponse, int64, pagination.Result, error) {
+	filter.OnlyAvailable = true
+	products, total, result, err := uc.listProducts(ctx, params, filter)
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, result, err
 	}
 
 	for i := range products {
@@ -54,38 +69,92 @@ func (uc *ProductUseCase) GetAll(
 		productResponses = append(productResponses, *p.ToResponse())
 	}
 
-	return productResponses, total, nil
+	return productResponses, total, result, nil
 }
 
-func (uc *ProductUseCase) GetAllToAdmin(
+// listProducts pages by offset or, when the client asked for it, by cursor.
+func (uc *ProductUseCase) listProducts(
+	ctx context.Context,
+	params pagination.Params,
+	filter ProductFilter) ([]Product, int64, pagination.Result, error) {
+	if params.CursorMode {
+		return uc.repo.GetAllByCursor(ctx, params.Cursor, params.PageSize, filter)
+	}
+
+	products, total, err := uc.repo.GetAll(ctx, params.Page, params.PageSize, filter)
+	return products, total, pagination.Result{}, err
+}
+
+// Search runs a full-text search over name, description, SKU and category
+// names of the available products, ordered by relevance.
+func (uc *ProductUseCase) Search(
 	ctx context.Context,
+	query string,
 	page int,
 	pageSize int,
-	nameFilter string,
-	categoryIDs []uint,
-	host string) ([]Product, int64, error) {
-	products, total, err := uc.repo.GetAll(ctx, page, pageSize, nameFilter, categoryIDs, false)
+	filter ProductFilter,
+	host string) ([]ProductSearchResponse, int64, error) {
+	filter.OnlyAvailable = true
+	results, total, err := uc.repo.Search(ctx, strings.TrimSpace(query), page, pageSize, filter)
 	if err != nil {
 		return nil, 0, err
 	}
 
+	searchResponses := make([]ProductSearchResponse, 0, len(results))
+	for _, result := range results {
+		for j := range result.Product.Images {
+			result.Product.Images[j].ImageURL = utils.GenerateImageURL(host, result.Product.Images[j].PublicID)
+		}
+
+		searchResponses = append(searchResponses, ProductSearchResponse{
+			ProductResponse: *result.Product.ToResponse(),
+			Rank:            result.Rank,
+			Snippet:         result.Snippet,
+		})
+	}
+
+	return searchResponses, total, nil
+}
+
+// GetFacets counts the available products matching the listing, optionally
+// narrowed by a text search, for the storefront filter sidebar.
+func (uc *ProductUseCase) GetFacets(
+	ctx context.Context,
+	query string,
+	filter ProductFilter,
+	priceBounds []float64) (*ProductFacets, error) {
+	filter.OnlyAvailable = true
+	return uc.repo.GetFacets(ctx, strings.TrimSpace(query), filter, priceBounds)
+}
+
+func (uc *ProductUseCase) GetAllToAdmin(
+	ctx context.Context,
+	params pagination.Params,
+	filter ProductFilter,
+	host string) ([]Product, int64, pagination.Result, error) {
+	products, total, result, err := uc.listProducts(ctx, params, filter)
+	if err != nil {
+		return nil, 0, result, err
+	}
+
 	for i := range products {
 		for j := range products[i].Images {
 			products[i].Images[j].ImageURL = utils.GenerateImageURL(host, products[i].Images[j].PublicID)
 		}
 	}
 
-	return products, total, nil
+	return products, total, result, nil
 }
 
-func (uc *ProductUseCase) Create(p Product) (*Product, error) {
+func (uc *ProductUseCase) Create(ctx context.Context, p Product) (*Product, error) {
 
-	if strings.TrimSpace(p.Slug) == "" {
-		p.Slug = GenerateSlug()
+	if err := validateProduct(&p); err != nil {
+		return nil, err
 	}
 
-	if strings.TrimSpace(p.Name) == "" {
-		return nil, errors.New("product name cannot be empty")
+	base := GenerateSlug(p.Name)
+	if strings.TrimSpace(p.Slug) != "" {
+		base = GenerateSlug(p.Slug)
 	}
 
 	var categories []category.Category
@@ -99,12 +168,21 @@ func (uc *ProductUseCase) Create(p Product) (*Product, error) {
 
 	p.Categories = categories
 
-	err := uc.repo.Create(p)
-	if err != nil {
-		return nil, err
-	}
+	for attempt := 1; ; attempt++ {
+		slug, err := uc.repo.UniqueSlug(base, 0, ReservedSlugs)
+		if err != nil {
+			return nil, err
+		}
+		p.Slug = slug
 
-	return &p, nil
+		err = uc.repo.Create(ctx, &p)
+		if err == nil {
+			return &p, nil
+		}
+		if !errors.Is(err, ErrDuplicateSlug) || attempt == maxSlugAttempts {
+			return nil, err
+		}
+	}
 }
 
 func (uc *ProductUseCase) GetBySlug(slug string) (*ProductResponse, error) {
@@ -113,9 +191,19 @@ func (uc *ProductUseCase) GetBySlug(slug string) (*ProductResponse, error) {
 		return nil, err
 	}
 
+	if !product.WithinAvailabilityWindow(time.Now()) {
+		return nil, ErrProductNotFound
+	}
+
 	return product.ToResponse(), nil
 }
 
+// ResolveSlugAlias returns the current slug of a product that was renamed
+// from the given slug.
+func (uc *ProductUseCase) ResolveSlugAlias(slug string) (string, error) {
+	return uc.repo.FindSlugAlias(slug)
+}
+
 func (uc *ProductUseCase) GetBySlugToAdmin(slug string) (*Product, error) {
 	product, err := uc.repo.FindBySlug(slug)
 	if err != nil {
@@ -125,52 +213,147 @@ func (uc *ProductUseCase) GetBySlugToAdmin(slug string) (*Product, error) {
 	return product, nil
 }
 
-func (uc *ProductUseCase) Delete(slug string) error {
+func (uc *ProductUseCase) Delete(ctx context.Context, slug string) error {
 
 	product, err := uc.repo.FindBySlug(slug)
+	if err != nil || product == nil {
+		return fmt.Errorf("product with slug %s not found: %w", slug, ErrProductNotFound)
+	}
+
+	// The product only goes to the trash; its images are removed from S3
+	// when PurgeTrash deletes it for good.
+	err = uc.repo.DeleteBySlug(ctx, product.ID)
 	if err != nil {
 		return err
 	}
 
-	if product == nil {
-		return fmt.Errorf("produto com slug %s não encontrado", slug)
-	}
+	return nil
+}
 
-	images, err := uc.imageRepo.FindByProductID(product.ID)
+func (uc *ProductUseCase) GetTrash(ctx context.Context, page int, pageSize int, host string) ([]Product, int64, error) {
+	products, total, err := uc.repo.GetTrash(ctx, page, pageSize)
 	if err != nil {
-		return fmt.Errorf("erro ao buscar imagens do produto: %v", err)
+		return nil, 0, err
 	}
 
-	for _, img := range images {
-		err := uc.imageService.DeleteImage(img.PublicID)
-		if err != nil {
-			return fmt.Errorf("erro ao deletar imagem %s do S3: %v", img.PublicID, err)
+	for i := range products {
+		for j := range products[i].Images {
+			products[i].Images[j].ImageURL = utils.GenerateImageURL(host, products[i].Images[j].PublicID)
 		}
 	}
 
-	err = uc.imageRepo.DeleteByProductID(product.ID)
+	return products, total, nil
+}
+
+func (uc *ProductUseCase) Restore(ctx context.Context, slug string, host string) (*Product, error) {
+	product, err := uc.repo.Restore(ctx, slug)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	for i := range product.Images {
+		product.Images[i].ImageURL = utils.GenerateImageURL(host, product.Images[i].PublicID)
 	}
 
-	err = uc.repo.DeleteBySlug(product.ID)
+	return product, nil
+}
+
+// PurgeTrash permanently deletes the products that have been in the trash
+// for longer than retention, removing their images from S3 afterwards. It
+// returns how many products were deleted.
+func (uc *ProductUseCase) PurgeTrash(ctx context.Context, retention time.Duration) (int, error) {
+	products, err := uc.repo.FindTrashedBefore(ctx, time.Now().Add(-retention))
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	return nil
+	purged := 0
+	for _, p := range products {
+		if err := uc.repo.Purge(ctx, p.ID); err != nil {
+			if errors.Is(err, ErrProductHasSales) {
+				continue
+			}
+			return purged, fmt.Errorf("erro ao excluir definitivamente o produto %s: %v", p.Slug, err)
+		}
+		purged++
+
+		for _, img := range p.Images {
+			if err := uc.imageService.DeleteImage(img.PublicID); err != nil {
+				log.Printf("erro ao deletar imagem %s do S3: %v", img.PublicID, err)
+			}
+		}
+	}
+
+	return purged, nil
+}
+
+func (uc *ProductUseCase) GetByCode(code string) (*Product, error) {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return nil, ErrProductNotFound
+	}
+
+	return uc.repo.FindByCode(barcode.Equivalents(code))
 }
 
-func (uc *ProductUseCase) Update(slug string, updatedProduct Product) (*Product, error) {
-	product, err := uc.repo.UpdateBySlug(slug, updatedProduct)
+// GetBarcodeImage renders the product barcode, falling back to its SKU when
+// no EAN-13/UPC-A code is registered.
+func (uc *ProductUseCase) GetBarcodeImage(slug string, format barcode.Format) ([]byte, string, error) {
+	product, err := uc.repo.FindBySlug(slug)
 	if err != nil {
-		return &product, err
+		return nil, "", ErrProductNotFound
+	}
+
+	var content string
+	switch {
+	case product.Barcode != nil:
+		content = *product.Barcode
+	case product.SKU != nil:
+		content = *product.SKU
+	default:
+		return nil, "", ErrProductHasNoCode
+	}
+
+	return barcode.Render(content, format)
+}
+
+// Update saves the product fields. When regenerateSlug is set the slug is
+// derived again from the new name and the old one is kept as an alias.
+func (uc *ProductUseCase) Update(ctx context.Context, slug string, updatedProduct Product, regenerateSlug bool) (*Product, error) {
+	if err := validateProduct(&updatedProduct); err != nil {
+		return nil, err
+	}
+
+	updatedProduct.Slug = ""
+	var existingID uint
+	if regenerateSlug {
+		existing, err := uc.repo.FindBySlug(slug)
+		if err != nil {
+			return nil, ErrProductNotFound
+		}
+		existingID = existing.ID
 	}
 
-	return &product, nil
+	for attempt := 1; ; attempt++ {
+		if regenerateSlug {
+			newSlug, err := uc.repo.UniqueSlug(GenerateSlug(updatedProduct.Name), existingID, ReservedSlugs)
+			if err != nil {
+				return nil, err
+			}
+			updatedProduct.Slug = newSlug
+		}
+
+		product, err := uc.repo.UpdateBySlug(ctx, slug, updatedProduct)
+		if err == nil {
+			return &product, nil
+		}
+		if !regenerateSlug || !errors.Is(err, ErrDuplicateSlug) || attempt == maxSlugAttempts {
+			return &product, err
+		}
+	}
 }
 
-func (uc *ProductUseCase) AddImagesToProduct(slug string, imageURLs []product_image.UploadedImage) error {
+func (uc *ProductUseCase) AddImagesToProduct(ctx context.Context, slug string, imageURLs []product_image.UploadedImage) error {
 	product, err := uc.repo.FindBySlug(slug)
 	if err != nil {
 		return err
@@ -180,7 +363,7 @@ func (uc *ProductUseCase) AddImagesToProduct(slug string, imageURLs []product_im
 		return fmt.Errorf("a product can have a maximum of 5 images")
 	}
 
-	err = uc.imageRepo.CreateManyImages(product.ID, imageURLs)
+	err = uc.imageRepo.CreateManyImages(ctx, product.ID, imageURLs)
 	if err != nil {
 		return err
 	}
@@ -196,7 +379,7 @@ func (uc *ProductUseCase) GetProductImages(productID uint) ([]product_image.Prod
 	return images, nil
 }
 
-func (uc *ProductUseCase) UpdateProductCategories(slug string, categoryIDs []int) error {
+func (uc *ProductUseCase) UpdateProductCategories(ctx context.Context, slug string, categoryIDs []int) error {
 	product, err := uc.repo.FindBySlug(slug)
 	if err != nil {
 		return fmt.Errorf("product with slug %s not found", slug)
@@ -215,14 +398,9 @@ func (uc *ProductUseCase) UpdateProductCategories(slug string, categoryIDs []int
 		categories = append(categories, *foundCategory)
 	}
 
-	err = uc.repo.ClearProductCategories(product.ID)
-	if err != nil {
-		return fmt.Errorf("failed to clear product categories: %v", err)
-	}
-
 	product.Categories = categories
 
-	err = uc.repo.UpdateProductCategories(product)
+	err = uc.repo.UpdateProductCategories(ctx, product)
 	if err != nil {
 		return fmt.Errorf("failed to update product categories: %v", err)
 	}
@@ -230,14 +408,14 @@ func (uc *ProductUseCase) UpdateProductCategories(slug string, categoryIDs []int
 	return nil
 }
 
-func (uc *ProductUseCase) SwitchAvailable(slug string) error {
+func (uc *ProductUseCase) SwitchAvailable(ctx context.Context, slug string) error {
 
 	product, err := uc.repo.FindBySlug(slug)
 	if err != nil {
 		return fmt.Errorf("product with slug %s not found", slug)
 	}
 
-	err = uc.repo.SwitchAvailable(*product)
+	err = uc.repo.SwitchAvailable(ctx, *product)
 	if err != nil {
 		return fmt.Errorf("houve um erro ao alterar o visibilidade do produto com slug %s, com o error: %v", slug, err)
 	}
@@ -245,16 +423,29 @@ func (uc *ProductUseCase) SwitchAvailable(slug string) error {
 	return nil
 }
 
-func (uc *ProductUseCase) ProductStockEntry(slug string, quantity int) error {
+// ApplyAvailabilitySchedule switches on the products whose available_from
+// has been reached and off the ones whose available_until has passed. It
+// returns how many products changed.
+func (uc *ProductUseCase) ApplyAvailabilitySchedule(ctx context.Context) (int, error) {
+	return uc.repo.ApplyAvailabilitySchedule(ctx, time.Now())
+}
 
-	product, err := uc.repo.FindBySlug(slug)
-	if err != nil {
-		return fmt.Errorf("product with slug %s not found", slug)
+func (uc *ProductUseCase) ProductStockEntry(ctx context.Context, slug string, quantity int, reason string, reference string) error {
+
+	if quantity <= 0 {
+		return ErrInvalidQuantity
 	}
 
-	product.Stock += quantity
+	movement := stock_movement.StockMovement{
+		Type:      stock_movement.EntryMovement,
+		Reason:    strings.TrimSpace(reason),
+		Reference: strings.TrimSpace(reference),
+	}
 
-	err = uc.repo.UpdateProductStock(product)
+	_, err := uc.repo.IncreaseStock(ctx, slug, quantity, movement)
+	if errors.Is(err, ErrProductNotFound) {
+		return fmt.Errorf("product with slug %s not found: %w", slug, err)
+	}
 	if err != nil {
 		return fmt.Errorf("houve um erro ao atualizar a quantidade do produto com slug %s, com o error: %v", slug, err)
 	}
@@ -262,22 +453,147 @@ func (uc *ProductUseCase) ProductStockEntry(slug string, quantity int) error {
 	return nil
 }
 
-func (uc *ProductUseCase) ProductStockOut(slug string, quantity int) error {
+func (uc *ProductUseCase) ProductStockOut(ctx context.Context, slug string, quantity int, reason string, reference string) error {
+
+	if quantity <= 0 {
+		return ErrInvalidQuantity
+	}
+
+	movement := stock_movement.StockMovement{
+		Type:      stock_movement.OutMovement,
+		Reason:    strings.TrimSpace(reason),
+		Reference: strings.TrimSpace(reference),
+	}
+
+	product, err := uc.repo.DecreaseStock(ctx, slug, quantity, movement)
+	if errors.Is(err, ErrProductNotFound) {
+		return fmt.Errorf("product with slug %s not found: %w", slug, err)
+	}
+	if errors.Is(err, ErrInsufficientStock) {
+		return fmt.Errorf("quantidade de saída %d excede o estoque atual: %w", quantity, err)
+	}
+	if err != nil {
+		return fmt.Errorf("houve um erro ao registrar a saída de estoque do produto com slug %s, com o error: %v", slug, err)
+	}
+
+	uc.alertService.NotifyIfCrossed(stock_alert.LowStockAlert{
+		ProductID:     product.ID,
+		Slug:          product.Slug,
+		Name:          product.Name,
+		PreviousStock: product.Stock + quantity,
+		Stock:         product.Stock,
+		MinStock:      product.MinStock,
+	})
+
+	return nil
+}
+
+func (uc *ProductUseCase) GetLowStock(ctx context.Context, page int, pageSize int, host string) ([]Product, int64, error) {
+	products, total, err := uc.repo.GetLowStock(ctx, page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for i := range products {
+		for j := range products[i].Images {
+			products[i].Images[j].ImageURL = utils.GenerateImageURL(host, products[i].Images[j].PublicID)
+		}
+	}
+
+	return products, total, nil
+}
+
+func (uc *ProductUseCase) GetStockMovements(
+	ctx context.Context,
+	slug string,
+	page int,
+	pageSize int,
+	movementTypes []stock_movement.MovementType,
+	startDate *time.Time,
+	endDate *time.Time) ([]stock_movement.StockMovement, int64, error) {
 
 	product, err := uc.repo.FindBySlug(slug)
 	if err != nil {
-		return fmt.Errorf("product with slug %s not found", slug)
+		return nil, 0, fmt.Errorf("product with slug %s not found: %w", slug, ErrProductNotFound)
 	}
 
-	if product.Stock < quantity {
-		return fmt.Errorf("quantidade de saída %d excede o estoque atual de %d", quantity, product.Stock)
+	for _, movementType := range movementTypes {
+		if !movementType.IsValid() {
+			return nil, 0, fmt.Errorf("%w: invalid stock movement type: %s", ErrInvalidMovementFilter, movementType)
+		}
+	}
+
+	if startDate != nil && endDate != nil && endDate.Before(*startDate) {
+		return nil, 0, fmt.Errorf("%w: end date cannot be before start date", ErrInvalidMovementFilter)
 	}
 
-	product.Stock -= quantity
+	return uc.movementRepo.GetByProductID(ctx, product.ID, page, pageSize, movementTypes, startDate, endDate)
+}
+
+func (uc *ProductUseCase) GetAudit(ctx context.Context, slug string, page int, pageSize int) ([]product_audit.ProductAudit, int64, error) {
 
-	err = uc.repo.UpdateProductStock(product)
+	product, err := uc.repo.FindBySlug(slug)
 	if err != nil {
-		return fmt.Errorf("houve um erro ao registrar a saída de estoque do produto com slug %s, com o error: %v", slug, err)
+		return nil, 0, fmt.Errorf("product with slug %s not found: %w", slug, ErrProductNotFound)
+	}
+
+	return uc.auditRepo.GetByProductID(ctx, product.ID, page, pageSize)
+}
+
+// validateProduct applies the rules every new product must follow, whether it
+// comes from the API or from an import file.
+func validateProduct(p *Product) error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return fmt.Errorf("%w: product name cannot be empty", ErrInvalidProduct)
+	}
+
+	if p.Price <= 0 {
+		return fmt.Errorf("%w: price must be greater than zero", ErrInvalidProduct)
+	}
+
+	if p.Cost < 0 {
+		return fmt.Errorf("%w: cost cannot be negative", ErrInvalidProduct)
+	}
+
+	if p.Stock < 0 {
+		return fmt.Errorf("%w: stock cannot be negative", ErrInvalidProduct)
+	}
+
+	if p.MinStock < 0 {
+		return fmt.Errorf("%w: min_stock cannot be negative", ErrInvalidProduct)
+	}
+
+	if p.AvailableFrom != nil && p.AvailableUntil != nil && !p.AvailableUntil.After(*p.AvailableFrom) {
+		return fmt.Errorf("%w: available_until must be after available_from", ErrInvalidProduct)
+	}
+
+	return normalizeCodes(p)
+}
+
+// normalizeCodes trims the SKU and barcode, clearing empty values so they do
+// not collide on the unique indexes, validates the barcode check digit and
+// stores UPC-A barcodes in their EAN-13 form.
+func normalizeCodes(p *Product) error {
+	if p.SKU != nil {
+		sku := strings.TrimSpace(*p.SKU)
+		p.SKU = &sku
+		if sku == "" {
+			p.SKU = nil
+		}
+	}
+
+	if p.Barcode != nil {
+		code := strings.TrimSpace(*p.Barcode)
+		p.Barcode = &code
+		if code == "" {
+			p.Barcode = nil
+		} else if err := barcode.Validate(code); err != nil {
+			return err
+		} else {
+			code = barcode.Normalize(code)
+			p.Barcode = &code
+		}
 	}
 
 	return nil
@@ -1,9 +1,27 @@
 package product_audit
 
 import (
+	"context"
 	"time"
 )
 
+const (
+	CreatedAction             = "created"
+	UpdatedAction             = "updated"
+	DeletedAction             = "deleted"
+	RestoredAction            = "restored"
+	PurgedAction              = "purged"
+	AvailabilitySwitchAction  = "availability_switched"
+	CategoriesLinkedAction    = "categories_linked"
+	ImagesAddedAction         = "images_added"
+	ImageRemovedAction        = "image_removed"
+	CoverChangedAction        = "cover_changed"
+	VariantCreatedAction      = "variant_created"
+	VariantUpdatedAction      = "variant_updated"
+	VariantDeletedAction      = "variant_deleted"
+	VariantAvailabilityAction = "variant_availability_switched"
+)
+
 type ProductAudit struct {
 	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
 	ProductID   uint      `gorm:"not null;index" json:"product_id"`
@@ -14,3 +32,11 @@ type ProductAudit struct {
 	Description string    `gorm:"type:text" json:"description"`
 	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
 }
+
+type ProductAuditRepository interface {
+	GetByProductID(
+		ctx context.Context,
+		productID uint,
+		page int,
+		pageSize int) ([]ProductAudit, int64, error)
+}
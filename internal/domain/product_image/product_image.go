@@ -1,6 +1,7 @@
 package product_image
 
 import (
+	"context"
 	"time"
 )
 
@@ -24,12 +25,11 @@ func (ProductImage) TableName() string {
 }
 
 type ImageRepository interface {
-	CreateManyImages(productID uint, imageURLs []UploadedImage) error
+	CreateManyImages(ctx context.Context, productID uint, imageURLs []UploadedImage) error
 	FindByProductID(productID uint) ([]ProductImage, error)
 	FindByPublicID(publicID string) (*ProductImage, error)
 	DeleteByProductID(productID uint) error
-	DeleteImage(uuid string) error
-	ResetCover(slug string) error
-	SetImageAsCover(uuid string) error
+	DeleteImage(ctx context.Context, uuid string) error
+	SetImageAsCover(ctx context.Context, slug string, uuid string) error
 	FindImageByPublicIdAndProductSlug(publicID string, slugId string) (*ProductImage, error)
 }
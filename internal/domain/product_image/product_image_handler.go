@@ -40,7 +40,7 @@ func (h *ImageHandler) GetImage(w http.ResponseWriter, r *http.Request) {
 func (h *ImageHandler) DeleteImage(w http.ResponseWriter, r *http.Request) {
 	uuid := chi.URLParam(r, "uuid")
 
-	err := h.useCase.DeleteImage(uuid)
+	err := h.useCase.DeleteImage(r.Context(), uuid)
 	if err != nil {
 		appError := error.NewAppError(err.Error(), http.StatusInternalServerError)
 		w.Header().Set("Content-Type", "application/json")
@@ -59,7 +59,7 @@ func (h *ImageHandler) SetImageAsCover(w http.ResponseWriter, r *http.Request) {
 	uuid := chi.URLParam(r, "uuid")
 	slug := chi.URLParam(r, "slug")
 
-	err := h.useCase.SetImageAsCover(uuid, slug)
+	err := h.useCase.SetImageAsCover(r.Context(), uuid, slug)
 	if err != nil {
 		appError := error.NewAppError("Erro ao definir a imagem como capa", http.StatusInternalServerError)
 		w.Header().Set("Content-Type", "application/json")
@@ -2,6 +2,7 @@ package product_image
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 
 	"github.com/reinaldo-silva/savina-stock/internal/domain/image_service"
@@ -33,7 +34,7 @@ func (uc *ImageUseCase) GetImage(publicID string) (*bytes.Buffer, string, error)
 	return uc.imageService.Download(publicID)
 }
 
-func (uc *ImageUseCase) DeleteImage(uuid string) error {
+func (uc *ImageUseCase) DeleteImage(ctx context.Context, uuid string) error {
 	image, err := uc.repo.FindByPublicID(uuid)
 	if err != nil {
 		return fmt.Errorf("erro ao buscar imagem com UUID %s: %v", uuid, err)
@@ -48,7 +49,7 @@ func (uc *ImageUseCase) DeleteImage(uuid string) error {
 		return fmt.Errorf("erro ao deletar imagem %s do provedor: %v", image.PublicID, err)
 	}
 
-	err = uc.repo.DeleteImage(uuid)
+	err = uc.repo.DeleteImage(ctx, uuid)
 	if err != nil {
 		return fmt.Errorf("erro ao deletar imagem %s do banco de dados: %v", uuid, err)
 	}
@@ -56,18 +57,13 @@ func (uc *ImageUseCase) DeleteImage(uuid string) error {
 	return nil
 }
 
-func (uc *ImageUseCase) SetImageAsCover(uuid string, slug string) error {
+func (uc *ImageUseCase) SetImageAsCover(ctx context.Context, uuid string, slug string) error {
 	_, err := uc.repo.FindImageByPublicIdAndProductSlug(uuid, slug)
 	if err != nil {
 		return fmt.Errorf("imagem com UUID %s não pertence ao produto com slug %s", uuid, slug)
 	}
 
-	err = uc.repo.ResetCover(slug)
-	if err != nil {
-		return fmt.Errorf("erro ao resetar imagem de capa anterior: %v", err)
-	}
-
-	err = uc.repo.SetImageAsCover(uuid)
+	err = uc.repo.SetImageAsCover(ctx, slug, uuid)
 	if err != nil {
 		return fmt.Errorf("erro ao definir imagem %s como capa: %v", uuid, err)
 	}
@@ -0,0 +1,61 @@
+package product_variant
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/reinaldo-silva/savina-stock/internal/domain/stock_movement"
+)
+
+var (
+	ErrVariantNotFound     = errors.New("variant not found")
+	ErrProductNotFound     = errors.New("product not found")
+	ErrDuplicateSKU        = errors.New("sku already in use")
+	ErrProductHasBaseStock = errors.New("product stock must be zero before adding its first variant")
+	ErrInsufficientStock   = errors.New("insufficient variant stock")
+	ErrInvalidQuantity     = errors.New("quantity must be greater than zero")
+	ErrVariantUnavailable  = errors.New("variant is not available")
+	ErrVariantHasSales     = errors.New("variant has sales and cannot be deleted, make it unavailable instead")
+)
+
+type ProductVariant struct {
+	ID         uint              `gorm:"primaryKey;autoIncrement" json:"id"`
+	ProductID  uint              `gorm:"not null;index" json:"product_id"`
+	SKU        string            `gorm:"type:varchar(64);uniqueIndex;not null" json:"sku"`
+	Attributes map[string]string `gorm:"type:jsonb;serializer:json" json:"attributes"` // Ex: {"size": "M", "color": "azul"}
+	Price      *float64          `gorm:"type:decimal(10,2)" json:"price"`              // Overrides the product price when set
+	Stock      int               `gorm:"not null;default:0" json:"stock"`
+	Available  bool              `gorm:"not null;default:true" json:"available"`
+	CreatedAt  time.Time         `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt  time.Time         `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// ProductStock is the stock of the product a variant belongs to, as left by a
+// variant stock change.
+type ProductStock struct {
+	ID       uint
+	Slug     string
+	Name     string
+	Stock    int
+	MinStock int
+}
+
+type VariantRepository interface {
+	FindByProductSlug(slug string) ([]ProductVariant, error)
+	FindByIDAndProductSlug(id uint, slug string) (*ProductVariant, error)
+	Create(ctx context.Context, slug string, variant *ProductVariant) error
+	Update(ctx context.Context, variant *ProductVariant) error
+	Delete(ctx context.Context, variant *ProductVariant) error
+	SwitchAvailable(ctx context.Context, variant ProductVariant) error
+	IncreaseStock(ctx context.Context, variantID uint, quantity int, movement stock_movement.StockMovement) (*ProductVariant, error)
+	DecreaseStock(ctx context.Context, variantID uint, quantity int, movement stock_movement.StockMovement) (*ProductVariant, *ProductStock, error)
+}
+
+// EffectivePrice returns the variant price override or the given product price.
+func (v *ProductVariant) EffectivePrice(productPrice float64) float64 {
+	if v.Price != nil {
+		return *v.Price
+	}
+	return productPrice
+}
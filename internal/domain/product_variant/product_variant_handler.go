@@ -0,0 +1,193 @@
+package product_variant
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	error_response "github.com/reinaldo-silva/savina-stock/package/response/error"
+	"github.com/reinaldo-silva/savina-stock/package/response/response"
+)
+
+type VariantHandler struct {
+	useCase *VariantUseCase
+}
+
+func NewVariantHandler(uc *VariantUseCase) *VariantHandler {
+	return &VariantHandler{uc}
+}
+
+type stockRequest struct {
+	Quantity  int    `json:"quantity"`
+	Reason    string `json:"reason"`
+	Reference string `json:"reference"`
+}
+
+func (h *VariantHandler) GetVariants(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "slug")
+
+	variants, err := h.useCase.GetByProductSlug(slug)
+	if err != nil {
+		h.sendErrorResponse(w, error_response.NewAppError(err.Error(), http.StatusInternalServerError))
+		return
+	}
+
+	appResponse := response.NewAppResponse(variants, "Variants fetched successfully", nil)
+	h.sendSuccessResponse(w, appResponse)
+}
+
+func (h *VariantHandler) CreateVariant(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "slug")
+
+	var newVariant ProductVariant
+	if err := json.NewDecoder(r.Body).Decode(&newVariant); err != nil {
+		h.sendErrorResponse(w, error_response.NewAppError("Invalid input data", http.StatusBadRequest))
+		return
+	}
+
+	createdVariant, err := h.useCase.Create(r.Context(), slug, newVariant)
+	if err != nil {
+		h.sendErrorResponse(w, error_response.NewAppError(err.Error(), errorStatusCode(err)))
+		return
+	}
+
+	appResponse := response.NewAppResponse(createdVariant, "Variant created successfully", nil, http.StatusCreated)
+	h.sendSuccessResponse(w, appResponse)
+}
+
+func (h *VariantHandler) UpdateVariant(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "slug")
+
+	variantID, err := strconv.Atoi(chi.URLParam(r, "variant_id"))
+	if err != nil {
+		h.sendErrorResponse(w, error_response.NewAppError("Invalid variant ID format", http.StatusBadRequest))
+		return
+	}
+
+	var updatedVariant ProductVariant
+	if err := json.NewDecoder(r.Body).Decode(&updatedVariant); err != nil {
+		h.sendErrorResponse(w, error_response.NewAppError("Invalid input data", http.StatusBadRequest))
+		return
+	}
+
+	variant, err := h.useCase.Update(r.Context(), slug, uint(variantID), updatedVariant)
+	if err != nil {
+		h.sendErrorResponse(w, error_response.NewAppError(err.Error(), errorStatusCode(err)))
+		return
+	}
+
+	appResponse := response.NewAppResponse(variant, "Variant updated successfully", nil)
+	h.sendSuccessResponse(w, appResponse)
+}
+
+func (h *VariantHandler) DeleteVariant(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "slug")
+
+	variantID, err := strconv.Atoi(chi.URLParam(r, "variant_id"))
+	if err != nil {
+		h.sendErrorResponse(w, error_response.NewAppError("Invalid variant ID format", http.StatusBadRequest))
+		return
+	}
+
+	err = h.useCase.Delete(r.Context(), slug, uint(variantID))
+	if err != nil {
+		h.sendErrorResponse(w, error_response.NewAppError(err.Error(), errorStatusCode(err)))
+		return
+	}
+
+	appResponse := response.NewAppResponse(nil, "Variant deleted successfully", nil)
+	h.sendSuccessResponse(w, appResponse)
+}
+
+func (h *VariantHandler) SwitchAvailable(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "slug")
+
+	variantID, err := strconv.Atoi(chi.URLParam(r, "variant_id"))
+	if err != nil {
+		h.sendErrorResponse(w, error_response.NewAppError("Invalid variant ID format", http.StatusBadRequest))
+		return
+	}
+
+	err = h.useCase.SwitchAvailable(r.Context(), slug, uint(variantID))
+	if err != nil {
+		h.sendErrorResponse(w, error_response.NewAppError(err.Error(), errorStatusCode(err)))
+		return
+	}
+
+	appResponse := response.NewAppResponse(nil, "Visibilidade da variação alterada com sucesso", nil)
+	h.sendSuccessResponse(w, appResponse)
+}
+
+func (h *VariantHandler) StockEntry(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "slug")
+
+	variantID, err := strconv.Atoi(chi.URLParam(r, "variant_id"))
+	if err != nil {
+		h.sendErrorResponse(w, error_response.NewAppError("Invalid variant ID format", http.StatusBadRequest))
+		return
+	}
+
+	var body stockRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		h.sendErrorResponse(w, error_response.NewAppError("Invalid request payload", http.StatusBadRequest))
+		return
+	}
+
+	variant, err := h.useCase.StockEntry(r.Context(), slug, uint(variantID), body.Quantity, body.Reason, body.Reference)
+	if err != nil {
+		h.sendErrorResponse(w, error_response.NewAppError(err.Error(), errorStatusCode(err)))
+		return
+	}
+
+	appResponse := response.NewAppResponse(variant, "Quantidade da variação atualizada com sucesso", nil)
+	h.sendSuccessResponse(w, appResponse)
+}
+
+func (h *VariantHandler) StockOut(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "slug")
+
+	variantID, err := strconv.Atoi(chi.URLParam(r, "variant_id"))
+	if err != nil {
+		h.sendErrorResponse(w, error_response.NewAppError("Invalid variant ID format", http.StatusBadRequest))
+		return
+	}
+
+	var body stockRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		h.sendErrorResponse(w, error_response.NewAppError("Invalid request payload", http.StatusBadRequest))
+		return
+	}
+
+	variant, err := h.useCase.StockOut(r.Context(), slug, uint(variantID), body.Quantity, body.Reason, body.Reference)
+	if err != nil {
+		h.sendErrorResponse(w, error_response.NewAppError(err.Error(), errorStatusCode(err)))
+		return
+	}
+
+	appResponse := response.NewAppResponse(variant, "Saída de estoque da variação registrada com sucesso", nil)
+	h.sendSuccessResponse(w, appResponse)
+}
+
+func errorStatusCode(err error) int {
+	switch {
+	case errors.Is(err, ErrVariantNotFound), errors.Is(err, ErrProductNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrInsufficientStock), errors.Is(err, ErrProductHasBaseStock), errors.Is(err, ErrDuplicateSKU), errors.Is(err, ErrVariantHasSales):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+func (h *VariantHandler) sendErrorResponse(w http.ResponseWriter, appError error_response.AppError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(appError.StatusCode)
+	json.NewEncoder(w).Encode(appError)
+}
+
+func (h *VariantHandler) sendSuccessResponse(w http.ResponseWriter, appResponse response.AppResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(appResponse.StatusCode)
+	json.NewEncoder(w).Encode(appResponse)
+}
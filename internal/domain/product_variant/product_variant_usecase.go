@@ -0,0 +1,169 @@
+package product_variant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/reinaldo-silva/savina-stock/internal/domain/stock_alert"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/stock_movement"
+)
+
+type VariantUseCase struct {
+	repo         VariantRepository
+	alertService *stock_alert.StockAlertService
+}
+
+func NewVariantUseCase(repo VariantRepository, alertService *stock_alert.StockAlertService) *VariantUseCase {
+	return &VariantUseCase{repo: repo, alertService: alertService}
+}
+
+// GetByProductSlug lists the variants shown in the storefront, leaving out
+// the unavailable ones.
+func (uc *VariantUseCase) GetByProductSlug(slug string) ([]ProductVariant, error) {
+	variants, err := uc.repo.FindByProductSlug(slug)
+	if err != nil {
+		return nil, err
+	}
+
+	available := make([]ProductVariant, 0, len(variants))
+	for _, v := range variants {
+		if v.Available {
+			available = append(available, v)
+		}
+	}
+	return available, nil
+}
+
+func (uc *VariantUseCase) Create(ctx context.Context, slug string, v ProductVariant) (*ProductVariant, error) {
+	if err := validateVariant(&v); err != nil {
+		return nil, err
+	}
+
+	if v.Stock < 0 {
+		return nil, errors.New("variant stock cannot be negative")
+	}
+
+	v.ID = 0
+	err := uc.repo.Create(ctx, slug, &v)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v, nil
+}
+
+// Update changes the descriptive fields of a variant. Stock is only changed
+// through stock entries, stock outs and sales so the ledger stays consistent.
+func (uc *VariantUseCase) Update(ctx context.Context, slug string, variantID uint, updated ProductVariant) (*ProductVariant, error) {
+	variant, err := uc.repo.FindByIDAndProductSlug(variantID, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateVariant(&updated); err != nil {
+		return nil, err
+	}
+
+	variant.SKU = updated.SKU
+	variant.Attributes = updated.Attributes
+	variant.Price = updated.Price
+
+	err = uc.repo.Update(ctx, variant)
+	if err != nil {
+		return nil, err
+	}
+
+	return variant, nil
+}
+
+func (uc *VariantUseCase) Delete(ctx context.Context, slug string, variantID uint) error {
+	variant, err := uc.repo.FindByIDAndProductSlug(variantID, slug)
+	if err != nil {
+		return err
+	}
+
+	return uc.repo.Delete(ctx, variant)
+}
+
+func (uc *VariantUseCase) SwitchAvailable(ctx context.Context, slug string, variantID uint) error {
+	variant, err := uc.repo.FindByIDAndProductSlug(variantID, slug)
+	if err != nil {
+		return err
+	}
+
+	return uc.repo.SwitchAvailable(ctx, *variant)
+}
+
+func (uc *VariantUseCase) StockEntry(ctx context.Context, slug string, variantID uint, quantity int, reason string, reference string) (*ProductVariant, error) {
+	if quantity <= 0 {
+		return nil, ErrInvalidQuantity
+	}
+
+	variant, err := uc.repo.FindByIDAndProductSlug(variantID, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	movement := stock_movement.StockMovement{
+		Type:      stock_movement.EntryMovement,
+		Reason:    strings.TrimSpace(reason),
+		Reference: strings.TrimSpace(reference),
+	}
+
+	return uc.repo.IncreaseStock(ctx, variant.ID, quantity, movement)
+}
+
+func (uc *VariantUseCase) StockOut(ctx context.Context, slug string, variantID uint, quantity int, reason string, reference string) (*ProductVariant, error) {
+	if quantity <= 0 {
+		return nil, ErrInvalidQuantity
+	}
+
+	variant, err := uc.repo.FindByIDAndProductSlug(variantID, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	movement := stock_movement.StockMovement{
+		Type:      stock_movement.OutMovement,
+		Reason:    strings.TrimSpace(reason),
+		Reference: strings.TrimSpace(reference),
+	}
+
+	updated, product, err := uc.repo.DecreaseStock(ctx, variant.ID, quantity, movement)
+	if errors.Is(err, ErrInsufficientStock) {
+		return nil, fmt.Errorf("quantidade de saída %d excede o estoque atual da variação %s: %w", quantity, variant.SKU, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	uc.alertService.NotifyIfCrossed(stock_alert.LowStockAlert{
+		ProductID:     product.ID,
+		Slug:          product.Slug,
+		Name:          product.Name,
+		PreviousStock: product.Stock + quantity,
+		Stock:         product.Stock,
+		MinStock:      product.MinStock,
+	})
+
+	return updated, nil
+}
+
+func validateVariant(v *ProductVariant) error {
+	v.SKU = strings.TrimSpace(v.SKU)
+	if v.SKU == "" {
+		return errors.New("variant sku cannot be empty")
+	}
+
+	if len(v.Attributes) == 0 {
+		return errors.New("variant must have at least one attribute")
+	}
+
+	if v.Price != nil && *v.Price <= 0 {
+		return errors.New("variant price must be greater than zero")
+	}
+
+	return nil
+}
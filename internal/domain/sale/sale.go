@@ -1,16 +1,47 @@
 package sale
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/reinaldo-silva/savina-stock/internal/domain/sale_item"
 )
 
+type Status string
+
+const (
+	CompletedStatus Status = "COMPLETED"
+	CanceledStatus  Status = "CANCELED"
+)
+
+var (
+	ErrSaleNotFound         = errors.New("sale not found")
+	ErrInvalidSale          = errors.New("invalid sale data")
+	ErrSaleAlreadyCanceled  = errors.New("sale is already canceled")
+	ErrDiscountExceedsTotal = fmt.Errorf("%w: discount cannot be greater than the sale total", ErrInvalidSale)
+)
+
 type Sale struct {
 	ID           uint                 `gorm:"primaryKey;autoIncrement" json:"id"`
 	Discount     float64              `gorm:"type:decimal(10,2);default:0" json:"discount"`
 	TotalAmount  float64              `gorm:"type:decimal(10,2);not null" json:"total_amount"`
+	Status       Status               `gorm:"type:varchar(20);not null;default:COMPLETED" json:"status"`
+	CanceledAt   *time.Time           `json:"canceled_at"`
 	CreatedAt    time.Time            `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt    time.Time            `gorm:"autoUpdateTime" json:"updated_at"`
 	SaleProducts []sale_item.SaleItem `gorm:"foreignKey:SaleID" json:"sale_products"`
 }
+
+type SaleRepository interface {
+	GetAll(
+		ctx context.Context,
+		page int,
+		pageSize int,
+		startDate *time.Time,
+		endDate *time.Time) ([]Sale, int64, error)
+	FindByID(id uint) (*Sale, error)
+	Create(ctx context.Context, sale *Sale) error
+	Cancel(ctx context.Context, id uint) (*Sale, error)
+}
@@ -0,0 +1,156 @@
+package sale
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/product"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/product_variant"
+	"github.com/reinaldo-silva/savina-stock/package/pagination"
+	error_response "github.com/reinaldo-silva/savina-stock/package/response/error"
+	"github.com/reinaldo-silva/savina-stock/package/response/response"
+)
+
+const dateLayout = "2006-01-02"
+
+type SaleHandler struct {
+	useCase     *SaleUseCase
+	maxPageSize int
+}
+
+func NewSaleHandler(uc *SaleUseCase, maxPageSize int) *SaleHandler {
+	return &SaleHandler{uc, maxPageSize}
+}
+
+func (h *SaleHandler) GetSales(w http.ResponseWriter, r *http.Request) {
+	startDateStr := r.URL.Query().Get("start_date")
+	endDateStr := r.URL.Query().Get("end_date")
+
+	params, err := pagination.FromRequest(r, h.maxPageSize)
+	if err != nil {
+		h.sendErrorResponse(w, error_response.NewAppError(err.Error(), http.StatusBadRequest))
+		return
+	}
+
+	var startDate, endDate *time.Time
+
+	if startDateStr != "" {
+		parsed, err := time.Parse(dateLayout, startDateStr)
+		if err != nil {
+			h.sendErrorResponse(w, error_response.NewAppError("Invalid start_date, expected format YYYY-MM-DD", http.StatusBadRequest))
+			return
+		}
+		startDate = &parsed
+	}
+
+	if endDateStr != "" {
+		parsed, err := time.Parse(dateLayout, endDateStr)
+		if err != nil {
+			h.sendErrorResponse(w, error_response.NewAppError("Invalid end_date, expected format YYYY-MM-DD", http.StatusBadRequest))
+			return
+		}
+		endDate = &parsed
+	}
+
+	sales, total, err := h.useCase.GetAll(r.Context(), params.Page, params.PageSize, startDate, endDate)
+	if err != nil {
+		if errors.Is(err, ErrInvalidSale) {
+			h.sendErrorResponse(w, error_response.NewAppError(err.Error(), http.StatusBadRequest))
+			return
+		}
+		log.Printf("failed to list sales: %v", err)
+		h.sendErrorResponse(w, error_response.NewAppError("Failed to fetch sales", http.StatusInternalServerError))
+		return
+	}
+
+	appResponse := response.NewAppResponse(sales, "Sales fetched successfully", &total)
+	h.sendSuccessResponse(w, appResponse)
+}
+
+func (h *SaleHandler) GetSaleByID(w http.ResponseWriter, r *http.Request) {
+	saleID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.sendErrorResponse(w, error_response.NewAppError("Invalid sale ID format", http.StatusBadRequest))
+		return
+	}
+
+	sale, err := h.useCase.GetByID(uint(saleID))
+	if err != nil {
+		if errors.Is(err, ErrSaleNotFound) {
+			h.sendErrorResponse(w, error_response.NewAppError("Sale not found", http.StatusNotFound))
+			return
+		}
+		h.sendErrorResponse(w, error_response.NewAppError("Failed to fetch sale", http.StatusInternalServerError))
+		return
+	}
+
+	appResponse := response.NewAppResponse(sale, "Sale fetched successfully", nil)
+	h.sendSuccessResponse(w, appResponse)
+}
+
+func (h *SaleHandler) CreateSale(w http.ResponseWriter, r *http.Request) {
+	var newSale Sale
+
+	err := json.NewDecoder(r.Body).Decode(&newSale)
+	if err != nil {
+		h.sendErrorResponse(w, error_response.NewAppError("Invalid input data", http.StatusBadRequest))
+		return
+	}
+
+	createdSale, err := h.useCase.Create(r.Context(), newSale)
+	if err != nil {
+		switch {
+		case errors.Is(err, product.ErrInsufficientStock), errors.Is(err, product_variant.ErrVariantUnavailable):
+			h.sendErrorResponse(w, error_response.NewAppError(err.Error(), http.StatusConflict))
+		case errors.Is(err, ErrInvalidSale), errors.Is(err, product.ErrProductHasVariants):
+			h.sendErrorResponse(w, error_response.NewAppError(err.Error(), http.StatusBadRequest))
+		default:
+			log.Printf("failed to create sale: %v", err)
+			h.sendErrorResponse(w, error_response.NewAppError("Failed to create sale", http.StatusInternalServerError))
+		}
+		return
+	}
+
+	appResponse := response.NewAppResponse(createdSale, "Sale created successfully", nil, http.StatusCreated)
+	h.sendSuccessResponse(w, appResponse)
+}
+
+func (h *SaleHandler) CancelSale(w http.ResponseWriter, r *http.Request) {
+	saleID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.sendErrorResponse(w, error_response.NewAppError("Invalid sale ID format", http.StatusBadRequest))
+		return
+	}
+
+	canceledSale, err := h.useCase.Cancel(r.Context(), uint(saleID))
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, ErrSaleNotFound) {
+			statusCode = http.StatusNotFound
+		} else if errors.Is(err, ErrSaleAlreadyCanceled) {
+			statusCode = http.StatusConflict
+		}
+		h.sendErrorResponse(w, error_response.NewAppError(err.Error(), statusCode))
+		return
+	}
+
+	appResponse := response.NewAppResponse(canceledSale, "Sale canceled successfully", nil)
+	h.sendSuccessResponse(w, appResponse)
+}
+
+func (h *SaleHandler) sendErrorResponse(w http.ResponseWriter, appError error_response.AppError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(appError.StatusCode)
+	json.NewEncoder(w).Encode(appError)
+}
+
+func (h *SaleHandler) sendSuccessResponse(w http.ResponseWriter, appResponse response.AppResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(appResponse.StatusCode)
+	json.NewEncoder(w).Encode(appResponse)
+}
@@ -0,0 +1,146 @@
+package sale
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/product"
+)
+
+// stubRepository fails every call with err.
+type stubRepository struct {
+	err error
+}
+
+func (r stubRepository) GetAll(ctx context.Context, page int, pageSize int, startDate *time.Time, endDate *time.Time) ([]Sale, int64, error) {
+	return nil, 0, r.err
+}
+
+func (r stubRepository) FindByID(id uint) (*Sale, error) {
+	return nil, r.err
+}
+
+func (r stubRepository) Create(ctx context.Context, sale *Sale) error {
+	return r.err
+}
+
+func (r stubRepository) Cancel(ctx context.Context, id uint) (*Sale, error) {
+	return nil, r.err
+}
+
+func newTestRouter(err error) http.Handler {
+	h := NewSaleHandler(NewSaleUseCase(stubRepository{err: err}, nil), 50)
+	router := chi.NewRouter()
+	router.Get("/sales", h.GetSales)
+	router.Get("/sales/{id}", h.GetSaleByID)
+	router.Post("/sales", h.CreateSale)
+	return router
+}
+
+func TestGetSaleByIDStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"not found", ErrSaleNotFound, http.StatusNotFound},
+		{"database error", errors.New("connection refused"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			newTestRouter(tt.err).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/sales/1", nil))
+			if w.Code != tt.status {
+				t.Fatalf("got status %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestCreateSaleStatus(t *testing.T) {
+	const validSale = `{"sale_products":[{"product_id":1,"quantity":1}]}`
+
+	tests := []struct {
+		name   string
+		body   string
+		err    error
+		status int
+	}{
+		{"no products", `{"sale_products":[]}`, nil, http.StatusBadRequest},
+		{"unknown product", validSale, fmt.Errorf("%w: product with ID 1 does not exist", ErrInvalidSale), http.StatusBadRequest},
+		{"discount over total", validSale, ErrDiscountExceedsTotal, http.StatusBadRequest},
+		{"insufficient stock", validSale, product.ErrInsufficientStock, http.StatusConflict},
+		{"database error", validSale, errors.New("connection refused"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			newTestRouter(tt.err).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/sales", strings.NewReader(tt.body)))
+			if w.Code != tt.status {
+				t.Fatalf("got status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status == http.StatusInternalServerError && strings.Contains(w.Body.String(), "connection refused") {
+				t.Fatal("the database error leaked into the response")
+			}
+		})
+	}
+}
+
+func TestGetSalesStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		err    error
+		status int
+	}{
+		{"end before start", "?start_date=2026-05-02&end_date=2026-05-01", nil, http.StatusBadRequest},
+		{"database error", "", errors.New("connection refused"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			newTestRouter(tt.err).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/sales"+tt.query, nil))
+			if w.Code != tt.status {
+				t.Fatalf("got status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if strings.Contains(w.Body.String(), "connection refused") {
+				t.Fatal("the database error leaked into the response")
+			}
+		})
+	}
+}
+
+// pageSizeRepository records the page size GetAll was called with.
+type pageSizeRepository struct {
+	stubRepository
+	pageSize int
+}
+
+func (r *pageSizeRepository) GetAll(ctx context.Context, page int, pageSize int, startDate *time.Time, endDate *time.Time) ([]Sale, int64, error) {
+	r.pageSize = pageSize
+	return nil, 0, nil
+}
+
+func TestGetSalesCapsThePageSize(t *testing.T) {
+	repo := &pageSizeRepository{}
+	h := NewSaleHandler(NewSaleUseCase(repo, nil), 50)
+
+	w := httptest.NewRecorder()
+	h.GetSales(w, httptest.NewRequest(http.MethodGet, "/sales?pageSize=100000", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+	if repo.pageSize != 50 {
+		t.Fatalf("listed %d sales per page, want 50", repo.pageSize)
+	}
+}
@@ -0,0 +1,105 @@
+package sale
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/reinaldo-silva/savina-stock/internal/domain/stock_alert"
+)
+
+type SaleUseCase struct {
+	repo         SaleRepository
+	alertService *stock_alert.StockAlertService
+}
+
+func NewSaleUseCase(repo SaleRepository, alertService *stock_alert.StockAlertService) *SaleUseCase {
+	return &SaleUseCase{
+		repo:         repo,
+		alertService: alertService,
+	}
+}
+
+func (uc *SaleUseCase) GetAll(
+	ctx context.Context,
+	page int,
+	pageSize int,
+	startDate *time.Time,
+	endDate *time.Time) ([]Sale, int64, error) {
+
+	if startDate != nil && endDate != nil && endDate.Before(*startDate) {
+		return nil, 0, fmt.Errorf("%w: end date cannot be before start date", ErrInvalidSale)
+	}
+
+	return uc.repo.GetAll(ctx, page, pageSize, startDate, endDate)
+}
+
+func (uc *SaleUseCase) GetByID(id uint) (*Sale, error) {
+	return uc.repo.FindByID(id)
+}
+
+func (uc *SaleUseCase) Create(ctx context.Context, s Sale) (*Sale, error) {
+
+	if len(s.SaleProducts) == 0 {
+		return nil, fmt.Errorf("%w: a sale must have at least one product", ErrInvalidSale)
+	}
+
+	if s.Discount < 0 {
+		return nil, fmt.Errorf("%w: discount cannot be negative", ErrInvalidSale)
+	}
+
+	for _, item := range s.SaleProducts {
+		if item.ProductID == 0 {
+			return nil, fmt.Errorf("%w: product_id is required for every sale item", ErrInvalidSale)
+		}
+		if item.Quantity <= 0 {
+			return nil, fmt.Errorf("%w: quantity for product %d must be greater than zero", ErrInvalidSale, item.ProductID)
+		}
+	}
+
+	s.ID = 0
+	s.Status = CompletedStatus
+	s.CanceledAt = nil
+
+	err := uc.repo.Create(ctx, &s)
+	if err != nil {
+		return nil, err
+	}
+
+	createdSale, err := uc.repo.FindByID(s.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	uc.notifyLowStock(createdSale)
+
+	return createdSale, nil
+}
+
+func (uc *SaleUseCase) Cancel(ctx context.Context, id uint) (*Sale, error) {
+	return uc.repo.Cancel(ctx, id)
+}
+
+func (uc *SaleUseCase) notifyLowStock(s *Sale) {
+	soldByProduct := make(map[uint]int)
+	for _, item := range s.SaleProducts {
+		soldByProduct[item.ProductID] += item.Quantity
+	}
+
+	for _, item := range s.SaleProducts {
+		quantity, pending := soldByProduct[item.ProductID]
+		if !pending {
+			continue
+		}
+		delete(soldByProduct, item.ProductID)
+
+		uc.alertService.NotifyIfCrossed(stock_alert.LowStockAlert{
+			ProductID:     item.Product.ID,
+			Slug:          item.Product.Slug,
+			Name:          item.Product.Name,
+			PreviousStock: item.Product.Stock + quantity,
+			Stock:         item.Product.Stock,
+			MinStock:      item.Product.MinStock,
+		})
+	}
+}
@@ -4,15 +4,18 @@ import (
 	"time"
 
 	"github.com/reinaldo-silva/savina-stock/internal/domain/product"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/product_variant"
 )
 
 type SaleItem struct {
-	ID        uint            `gorm:"primaryKey;autoIncrement" json:"id"`
-	SaleID    uint            `gorm:"not null;index" json:"sale_id"`
-	ProductID uint            `gorm:"not null;index" json:"product_id"`
-	Quantity  int             `gorm:"not null" json:"quantity"`
-	UnitPrice float64         `gorm:"type:decimal(10,2);not null" json:"unit_price"`
-	SubTotal  float64         `gorm:"type:decimal(10,2);not null" json:"subtotal"`
-	CreatedAt time.Time       `gorm:"autoCreateTime" json:"created_at"`
-	Product   product.Product `gorm:"foreignKey:ProductID" json:"product"`
+	ID        uint                            `gorm:"primaryKey;autoIncrement" json:"id"`
+	SaleID    uint                            `gorm:"not null;index" json:"sale_id"`
+	ProductID uint                            `gorm:"not null;index" json:"product_id"`
+	VariantID *uint                           `gorm:"index" json:"variant_id"` // Required when the product has variants
+	Quantity  int                             `gorm:"not null" json:"quantity"`
+	UnitPrice float64                         `gorm:"type:decimal(10,2);not null" json:"unit_price"`
+	SubTotal  float64                         `gorm:"type:decimal(10,2);not null" json:"subtotal"`
+	CreatedAt time.Time                       `gorm:"autoCreateTime" json:"created_at"`
+	Product   product.Product                 `gorm:"foreignKey:ProductID" json:"product"`
+	Variant   *product_variant.ProductVariant `gorm:"foreignKey:VariantID" json:"variant,omitempty"`
 }
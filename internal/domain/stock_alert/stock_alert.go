@@ -0,0 +1,67 @@
+package stock_alert
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/reinaldo-silva/savina-stock/internal/infrastructure/notifier"
+)
+
+const LowStockEvent = "product.low_stock"
+
+type LowStockAlert struct {
+	ProductID     uint   `json:"product_id"`
+	Slug          string `json:"slug"`
+	Name          string `json:"name"`
+	PreviousStock int    `json:"previous_stock"`
+	Stock         int    `json:"stock"`
+	MinStock      int    `json:"min_stock"`
+}
+
+type StockAlertService struct {
+	notifier notifier.Implementation
+}
+
+func NewStockAlertService(notifier notifier.Implementation) *StockAlertService {
+	return &StockAlertService{notifier}
+}
+
+// CrossedThreshold reports whether a stock-out took the product from above its
+// reorder point to at or below it. Only that transition triggers an alert, so
+// further stock-outs while the product is already low stay silent.
+func CrossedThreshold(previousStock int, currentStock int, minStock int) bool {
+	return minStock > 0 && previousStock > minStock && currentStock <= minStock
+}
+
+// NotifyIfCrossed sends the alert in the background so that a slow or failing
+// notifier never blocks the stock-out request.
+func (se *StockAlertService) NotifyIfCrossed(alert LowStockAlert) {
+	if se == nil || se.notifier == nil {
+		return
+	}
+
+	if !CrossedThreshold(alert.PreviousStock, alert.Stock, alert.MinStock) {
+		return
+	}
+
+	notification := notifier.Notification{
+		Event:   LowStockEvent,
+		Subject: fmt.Sprintf("Estoque baixo: %s", alert.Name),
+		Message: fmt.Sprintf("O produto %s (%s) está com %d unidade(s) em estoque, abaixo ou igual ao ponto de reposição de %d.",
+			alert.Name, alert.Slug, alert.Stock, alert.MinStock),
+		Data: map[string]interface{}{
+			"product_id":     alert.ProductID,
+			"slug":           alert.Slug,
+			"name":           alert.Name,
+			"previous_stock": alert.PreviousStock,
+			"stock":          alert.Stock,
+			"min_stock":      alert.MinStock,
+		},
+	}
+
+	go func() {
+		if err := se.notifier.Notify(notification); err != nil {
+			log.Printf("failed to send low stock alert for product %s: %v", alert.Slug, err)
+		}
+	}()
+}
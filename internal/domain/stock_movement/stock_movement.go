@@ -0,0 +1,48 @@
+package stock_movement
+
+import (
+	"context"
+	"time"
+)
+
+type MovementType string
+
+const (
+	EntryMovement      MovementType = "ENTRY"
+	OutMovement        MovementType = "OUT"
+	AdjustmentMovement MovementType = "ADJUSTMENT"
+	SaleMovement       MovementType = "SALE"
+	ReturnMovement     MovementType = "RETURN"
+)
+
+type StockMovement struct {
+	ID        uint         `gorm:"primaryKey;autoIncrement" json:"id"`
+	ProductID uint         `gorm:"not null;index" json:"product_id"`
+	VariantID *uint        `gorm:"index" json:"variant_id"`
+	UserID    uint         `gorm:"index" json:"user_id"`
+	Type      MovementType `gorm:"type:varchar(20);not null;index" json:"type"`
+	Quantity  int          `gorm:"not null" json:"quantity"` // Signed: positive adds to stock, negative removes
+	Balance   int          `gorm:"not null" json:"balance"`  // Stock of the product, or of the variant when set, after this movement
+	Reason    string       `gorm:"type:varchar(255)" json:"reason"`
+	Reference string       `gorm:"type:varchar(100);index" json:"reference"` // Ex: "sale:42"
+	CreatedAt time.Time    `gorm:"autoCreateTime;index" json:"created_at"`
+}
+
+type StockMovementRepository interface {
+	GetByProductID(
+		ctx context.Context,
+		productID uint,
+		page int,
+		pageSize int,
+		movementTypes []MovementType,
+		startDate *time.Time,
+		endDate *time.Time) ([]StockMovement, int64, error)
+}
+
+func (t MovementType) IsValid() bool {
+	switch t {
+	case EntryMovement, OutMovement, AdjustmentMovement, SaleMovement, ReturnMovement:
+		return true
+	}
+	return false
+}
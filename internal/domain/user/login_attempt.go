@@ -0,0 +1,85 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+var ErrTooManyLoginAttempts = errors.New("too many failed sign-in attempts")
+
+// LoginAttempt counts the consecutive failed sign-ins of an account or of an
+// IP address, identified by Key.
+type LoginAttempt struct {
+	Key           string    `gorm:"type:varchar(255);primaryKey"`
+	Failures      int       `gorm:"not null"`
+	LastFailureAt time.Time `gorm:"not null;index"`
+}
+
+type LoginAttemptStore interface {
+	// Get returns nil when the key has no failure recorded.
+	Get(key string) (*LoginAttempt, error)
+	// RecordFailure adds a failure to the key and returns the new count in
+	// the same atomic step. The count restarts when the previous failure
+	// happened before resetBefore.
+	RecordFailure(key string, now time.Time, resetBefore time.Time) (*LoginAttempt, error)
+	// Forgive takes back one failure from the key.
+	Forgive(key string) error
+	Reset(key string) error
+	DeleteStale(before time.Time) (int64, error)
+}
+
+// LoginThrottle is the brute-force policy of the sign-in. After each failure
+// the next attempt is delayed exponentially, starting at BackoffBase, and
+// after MaxFailures (IPMaxFailures for an address) the key is locked for
+// Lockout. Failures older than Lockout are forgotten.
+type LoginThrottle struct {
+	MaxFailures   int
+	IPMaxFailures int
+	BackoffBase   time.Duration
+	Lockout       time.Duration
+}
+
+// LoginThrottledError is returned while an account or address must wait
+// before trying again.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func (e *LoginThrottledError) Error() string {
+	if e.Locked {
+		return fmt.Sprintf("%s, try again in %s", ErrTooManyLoginAttempts, e.RetryAfter.Round(time.Second))
+	}
+	return fmt.Sprintf("%s, wait %s before trying again", ErrTooManyLoginAttempts, e.RetryAfter.Round(time.Second))
+}
+
+func (e *LoginThrottledError) Is(target error) bool {
+	return target == ErrTooManyLoginAttempts
+}
+
+func accountAttemptKey(email string) string {
+	return "account:" + NormalizeEmail(email)
+}
+
+func ipAttemptKey(ip string) string {
+	return "ip:" + ip
+}
+
+// blockedUntil is when the key may try again, given its failures so far.
+func (t LoginThrottle) blockedUntil(attempt *LoginAttempt, maxFailures int) (time.Time, bool) {
+	if attempt == nil || attempt.Failures == 0 {
+		return time.Time{}, false
+	}
+
+	if attempt.Failures >= maxFailures {
+		return attempt.LastFailureAt.Add(t.Lockout), true
+	}
+
+	backoff := time.Duration(float64(t.BackoffBase) * math.Pow(2, float64(attempt.Failures-1)))
+	if backoff > t.Lockout || backoff <= 0 {
+		backoff = t.Lockout
+	}
+	return attempt.LastFailureAt.Add(backoff), false
+}
@@ -0,0 +1,153 @@
+package user_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/reinaldo-silva/savina-stock/internal/domain/user"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/user/usertest"
+	memory_login_attempt "github.com/reinaldo-silva/savina-stock/internal/infrastructure/login_attempt/memory"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	testEmail    = "ana@example.com"
+	testPassword = "secret123"
+	testIP       = "203.0.113.7"
+)
+
+func newThrottledUseCase(t *testing.T) (*user.UserUseCase, user.LoginAttemptStore, *user.User) {
+	t.Helper()
+	t.Setenv("LOGIN_MAX_FAILURES", "5")
+	t.Setenv("LOGIN_IP_MAX_FAILURES", "20")
+	t.Setenv("LOGIN_LOCKOUT_MINUTES", "15")
+	t.Setenv("LOGIN_BACKOFF_BASE_SECONDS", "1")
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := usertest.NewUserRepository()
+	u := &user.User{Name: "Ana", Email: testEmail, Password: string(hash)}
+	if err := repo.Create(u); err != nil {
+		t.Fatal(err)
+	}
+
+	attempts := memory_login_attempt.NewMemoryLoginAttemptStore()
+	sessions := usertest.NewSessionRepository()
+	uc := user.NewUserUseCase(repo, sessions, usertest.NewUserTokenRepository(repo, sessions), attempts, nil)
+	return uc, attempts, u
+}
+
+func TestSignInConcurrentGuessesCannotExceedMaxFailures(t *testing.T) {
+	uc, _, _ := newThrottledUseCase(t)
+
+	const guesses = 50
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	checked, throttled := 0, 0
+
+	for i := 0; i < guesses; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := uc.SignInUseCase(testEmail, "wrong-password", testIP)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if errors.Is(err, user.ErrTooManyLoginAttempts) {
+				throttled++
+			} else {
+				checked++
+			}
+		}()
+	}
+	wg.Wait()
+
+	if checked > 5 {
+		t.Fatalf("%d guesses had their password checked, want at most 5", checked)
+	}
+	if checked+throttled != guesses {
+		t.Fatalf("got %d checked and %d throttled, want %d in total", checked, throttled, guesses)
+	}
+}
+
+func TestSignInBacksOffAfterFailure(t *testing.T) {
+	uc, _, _ := newThrottledUseCase(t)
+
+	if _, _, err := uc.SignInUseCase(testEmail, "wrong-password", testIP); errors.Is(err, user.ErrTooManyLoginAttempts) {
+		t.Fatalf("first attempt was throttled: %v", err)
+	}
+
+	_, _, err := uc.SignInUseCase(testEmail, testPassword, testIP)
+	var throttled *user.LoginThrottledError
+	if !errors.As(err, &throttled) {
+		t.Fatalf("retry right after a failure: got %v, want a throttle error", err)
+	}
+	if throttled.Locked || throttled.RetryAfter <= 0 || throttled.RetryAfter > time.Second {
+		t.Fatalf("got %+v, want a backoff of up to 1s", throttled)
+	}
+}
+
+func TestSignInLocksAccountAfterMaxFailures(t *testing.T) {
+	uc, attempts, _ := newThrottledUseCase(t)
+
+	// Five failures whose backoff is already over.
+	past := time.Now().Add(-5 * time.Minute)
+	for i := 0; i < 5; i++ {
+		if _, err := attempts.RecordFailure("account:"+testEmail, past, past.Add(-time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, _, err := uc.SignInUseCase(testEmail, testPassword, testIP)
+	var throttled *user.LoginThrottledError
+	if !errors.As(err, &throttled) || !throttled.Locked {
+		t.Fatalf("got %v, want the account locked even with the right password", err)
+	}
+}
+
+func TestSignInSuccessResetsAccountAndForgivesAddress(t *testing.T) {
+	uc, attempts, _ := newThrottledUseCase(t)
+
+	past := time.Now().Add(-time.Minute)
+	for _, key := range []string{"account:" + testEmail, "ip:" + testIP} {
+		for i := 0; i < 2; i++ {
+			if _, err := attempts.RecordFailure(key, past, past.Add(-time.Hour)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if _, _, err := uc.SignInUseCase(testEmail, testPassword, testIP); err != nil {
+		t.Fatalf("sign-in failed: %v", err)
+	}
+
+	if attempt, _ := attempts.Get("account:" + testEmail); attempt != nil {
+		t.Fatalf("account failures were kept: %+v", attempt)
+	}
+	if attempt, _ := attempts.Get("ip:" + testIP); attempt == nil || attempt.Failures != 2 {
+		t.Fatalf("got address failures %+v, want the 2 earlier failures kept", attempt)
+	}
+}
+
+func TestUnlockAccount(t *testing.T) {
+	uc, attempts, u := newThrottledUseCase(t)
+
+	for i := 0; i < 5; i++ {
+		if _, err := attempts.RecordFailure("account:"+testEmail, time.Now(), time.Now().Add(-time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := uc.UnlockAccount(u.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := uc.SignInUseCase(testEmail, testPassword, testIP); err != nil {
+		t.Fatalf("sign-in after unlock failed: %v", err)
+	}
+}
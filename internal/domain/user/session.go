@@ -0,0 +1,78 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token already used, the session was revoked")
+)
+
+// Session is one sign-in of a user. Access tokens carry its id, so revoking
+// the session invalidates them before they expire.
+type Session struct {
+	ID         uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID     uint       `gorm:"not null;index" json:"user_id"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// RefreshToken is stored as the SHA-256 of the opaque token handed to the
+// client. Each refresh marks the token as used and issues a new one for the
+// same session; presenting a used token again revokes the whole session.
+type RefreshToken struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	SessionID uint      `gorm:"not null;index"`
+	Session   Session   `gorm:"foreignKey:SessionID;constraint:OnDelete:CASCADE"`
+	TokenHash string    `gorm:"type:varchar(64);uniqueIndex;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+type SessionRepository interface {
+	Create(session *Session, token *RefreshToken) error
+	FindRefreshToken(tokenHash string) (*RefreshToken, error)
+	Rotate(used *RefreshToken, next *RefreshToken) error
+	Revoke(sessionID uint) error
+	RevokeAllForUser(userID uint) error
+	RevokeOthers(userID uint, keepSessionID uint) error
+	IsActive(sessionID uint) (bool, error)
+	DeleteExpired(before time.Time) (int64, error)
+}
+
+// TokenPair is what a sign-in or a refresh returns to the client.
+type TokenPair struct {
+	AccessToken           string    `json:"token"`
+	AccessTokenExpiresAt  time.Time `json:"token_expires_at"`
+	RefreshToken          string    `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
+}
+
+func (s *Session) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// newOpaqueToken returns a random token and the hash to store for it.
+func newOpaqueToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return token, HashToken(token), nil
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,25 +1,41 @@
 package user
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/reinaldo-silva/savina-stock/package/pagination"
 	"gorm.io/gorm"
 )
 
 type Role string
 
+var (
+	ErrEmailInUse           = errors.New("email is already in use")
+	ErrUserNotFound         = errors.New("user not found")
+	ErrUserDeactivated      = errors.New("user account is deactivated")
+	ErrInvalidRole          = errors.New("invalid role")
+	ErrLastAdmin            = errors.New("the last active admin cannot be demoted or deactivated")
+	ErrCannotDeactivateSelf = errors.New("you cannot deactivate your own account")
+)
+
 const (
 	AdminRole  Role = "ADMIN"
 	ClientRole Role = "CLIENT"
 )
 
 type User struct {
-	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
-	Name      string    `gorm:"type:varchar(100);not null" json:"name"`
-	Email     string    `gorm:"type:varchar(150);unique;not null" json:"email"`
-	Password  string    `gorm:"type:varchar(255);not null" json:"password"`
-	Role      Role      `gorm:"type:varchar(20);not null;default:CLIENT" json:"role"`
+	ID       uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name     string `gorm:"type:varchar(100);not null" json:"name"`
+	Email    string `gorm:"type:varchar(150);unique;not null" json:"email"`
+	Password string `gorm:"type:varchar(255);not null" json:"password"`
+	Role     Role   `gorm:"type:varchar(20);not null;default:CLIENT" json:"role"`
+	// EmailVerifiedAt is set once the user follows the verification link.
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	// Active is false for deactivated accounts, which can no longer sign in.
+	// Users are never deleted, since audits and movements reference them.
+	Active    bool      `gorm:"not null;default:true" json:"active"`
 	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
 }
@@ -27,25 +43,41 @@ type User struct {
 type UserRepository interface {
 	GetAll(page int,
 		pageSize int) ([]User, int64, error)
-	Create(user User) error
+	GetAllByCursor(cursor *pagination.Cursor,
+		pageSize int) ([]User, int64, pagination.Result, error)
+	Create(user *User) error
 	FindByEmail(email string) (*User, error)
+	FindByID(id uint) (*User, error)
+	UpdatePassword(id uint, passwordHash string) error
+	MarkEmailVerified(id uint, verifiedAt time.Time) error
+	Update(user *User) error
+	// UpdateRole and SetActive refuse, with ErrLastAdmin, to leave the
+	// system without an active admin.
+	UpdateRole(id uint, role Role) error
+	SetActive(id uint, active bool) error
 }
 
 type UserResponse struct {
-	ID        uint      `json:"id"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID              uint       `json:"id"`
+	Name            string     `json:"name"`
+	Email           string     `json:"email"`
+	Role            Role       `json:"role"`
+	Active          bool       `json:"active"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
 }
 
 func (u *User) ToResponse() *UserResponse {
 	return &UserResponse{
-		ID:        u.ID,
-		Name:      u.Name,
-		Email:     u.Email,
-		CreatedAt: u.CreatedAt,
-		UpdatedAt: u.UpdatedAt,
+		ID:              u.ID,
+		Name:            u.Name,
+		Email:           u.Email,
+		Role:            u.Role,
+		Active:          u.Active,
+		EmailVerifiedAt: u.EmailVerifiedAt,
+		CreatedAt:       u.CreatedAt,
+		UpdatedAt:       u.UpdatedAt,
 	}
 }
 
@@ -62,8 +94,12 @@ func (u *User) validateRole() error {
 		return nil
 	}
 
-	if u.Role != AdminRole && u.Role != ClientRole {
+	if !u.Role.IsValid() {
 		return fmt.Errorf("invalid role: %s", u.Role)
 	}
 	return nil
 }
+
+func (r Role) IsValid() bool {
+	return r == AdminRole || r == ClientRole
+}
@@ -2,45 +2,51 @@ package user
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/reinaldo-silva/savina-stock/package/pagination"
 	"github.com/reinaldo-silva/savina-stock/package/response/error"
 	"github.com/reinaldo-silva/savina-stock/package/response/response"
+	"github.com/reinaldo-silva/savina-stock/utils"
 )
 
 type UserHandler struct {
-	useCase *UserUseCase
+	useCase     *UserUseCase
+	maxPageSize int
 }
 
-func NewUserHandler(uc *UserUseCase) *UserHandler {
-	return &UserHandler{uc}
+func NewUserHandler(uc *UserUseCase, maxPageSize int) *UserHandler {
+	return &UserHandler{uc, maxPageSize}
 }
 
 func (h *UserHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
-	pageStr := r.URL.Query().Get("page")
-	pageSizeStr := r.URL.Query().Get("pageSize")
-
-	page, err := strconv.Atoi(pageStr)
-	if err != nil || page < 1 {
-		page = 1
-	}
-
-	pageSize, err := strconv.Atoi(pageSizeStr)
-	if err != nil || pageSize < 1 {
-		pageSize = 10
+	params, err := pagination.FromRequest(r, h.maxPageSize)
+	if err != nil {
+		appError := error.NewAppError(err.Error(), http.StatusBadRequest)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
 	}
 
-	users, total, err := h.useCase.GetAll(page, pageSize)
+	users, total, result, err := h.useCase.GetAll(params)
 	if err != nil {
-		appError := error.NewAppError(err.Error(), http.StatusInternalServerError)
+		status := http.StatusInternalServerError
+		if errors.Is(err, pagination.ErrInvalidCursor) {
+			status = http.StatusBadRequest
+		}
+		appError := error.NewAppError(err.Error(), status)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(appError.StatusCode)
 		json.NewEncoder(w).Encode(appError)
 		return
 	}
 
-	appResponse := response.NewAppResponse(users, "Users fetched successfully", &total)
+	appResponse := response.NewAppResponse(users, "Users fetched successfully", &total).
+		WithCursors(result.NextCursor, result.PrevCursor)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -48,7 +54,7 @@ func (h *UserHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
-	var newUser User
+	var newUser CreateUserRequest
 
 	err := json.NewDecoder(r.Body).Decode(&newUser)
 	if err != nil {
@@ -61,6 +67,14 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 
 	createdUser, err := h.useCase.Create(newUser)
 	if err != nil {
+		var validation *ValidationError
+		if errors.As(err, &validation) {
+			appError := error.NewAppError("Invalid user data", http.StatusBadRequest).WithErrors(validation.Errors)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(appError.StatusCode)
+			json.NewEncoder(w).Encode(appError)
+			return
+		}
 		appError := error.NewAppError(err.Error(), http.StatusInternalServerError)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(appError.StatusCode)
@@ -73,3 +87,368 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(appResponse.StatusCode)
 	json.NewEncoder(w).Encode(appResponse)
 }
+
+// RevokeSessions signs a user out of every session, e.g. when an employee
+// leaves.
+func (h *UserHandler) RevokeSessions(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		appError := error.NewAppError("Invalid user ID format", http.StatusBadRequest)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	if err := h.useCase.SignOutAll(uint(userID)); err != nil {
+		appError := error.NewAppError(err.Error(), http.StatusInternalServerError)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	appResponse := response.NewAppResponse(nil, "User sessions revoked successfully", nil)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(appResponse)
+}
+
+// UnlockUser clears the failed sign-ins of a locked out user.
+func (h *UserHandler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		appError := error.NewAppError("Invalid user ID format", http.StatusBadRequest)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	if err := h.useCase.UnlockAccount(uint(userID)); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrUserNotFound) {
+			status = http.StatusNotFound
+		}
+		appError := error.NewAppError(err.Error(), status)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	appResponse := response.NewAppResponse(nil, "User unlocked successfully", nil)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(appResponse)
+}
+
+func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		appError := error.NewAppError("Invalid user ID format", http.StatusBadRequest)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	foundUser, err := h.useCase.GetByID(uint(userID))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrUserNotFound) {
+			status = http.StatusNotFound
+		}
+		appError := error.NewAppError(err.Error(), status)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	appResponse := response.NewAppResponse(foundUser, "User fetched successfully", nil)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(appResponse)
+}
+
+func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		appError := error.NewAppError("Invalid user ID format", http.StatusBadRequest)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	var updateData UpdateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&updateData); err != nil {
+		appError := error.NewAppError("Invalid input data", http.StatusBadRequest)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	updatedUser, err := h.useCase.Update(uint(userID), updateData)
+	if err != nil {
+		var validation *ValidationError
+		if errors.As(err, &validation) {
+			appError := error.NewAppError("Invalid user data", http.StatusBadRequest).WithErrors(validation.Errors)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(appError.StatusCode)
+			json.NewEncoder(w).Encode(appError)
+			return
+		}
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrUserNotFound) {
+			status = http.StatusNotFound
+		}
+		appError := error.NewAppError(err.Error(), status)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	appResponse := response.NewAppResponse(updatedUser, "User updated successfully", nil)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(appResponse)
+}
+
+// DeactivateUser answers DELETE /users/{id}. Users are deactivated rather than
+// deleted, and can be brought back with ActivateUser.
+func (h *UserHandler) DeactivateUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		appError := error.NewAppError("Invalid user ID format", http.StatusBadRequest)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	actorID, _ := utils.GetUserIDFromContext(r.Context())
+
+	deactivatedUser, err := h.useCase.Deactivate(actorID, uint(userID))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrUserNotFound) {
+			status = http.StatusNotFound
+		}
+		if errors.Is(err, ErrLastAdmin) || errors.Is(err, ErrCannotDeactivateSelf) {
+			status = http.StatusConflict
+		}
+		appError := error.NewAppError(err.Error(), status)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	appResponse := response.NewAppResponse(deactivatedUser, "User deactivated successfully", nil)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(appResponse)
+}
+
+func (h *UserHandler) ActivateUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		appError := error.NewAppError("Invalid user ID format", http.StatusBadRequest)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	activatedUser, err := h.useCase.Activate(uint(userID))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrUserNotFound) {
+			status = http.StatusNotFound
+		}
+		appError := error.NewAppError(err.Error(), status)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	appResponse := response.NewAppResponse(activatedUser, "User activated successfully", nil)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(appResponse)
+}
+
+func (h *UserHandler) ChangeUserRole(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		appError := error.NewAppError("Invalid user ID format", http.StatusBadRequest)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	var roleData struct {
+		Role Role `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&roleData); err != nil {
+		appError := error.NewAppError("Invalid input data", http.StatusBadRequest)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	updatedUser, err := h.useCase.ChangeRole(uint(userID), roleData.Role)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrUserNotFound) {
+			status = http.StatusNotFound
+		}
+		if errors.Is(err, ErrInvalidRole) {
+			status = http.StatusBadRequest
+		}
+		if errors.Is(err, ErrLastAdmin) {
+			status = http.StatusConflict
+		}
+		appError := error.NewAppError(err.Error(), status)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	appResponse := response.NewAppResponse(updatedUser, "User role updated successfully", nil)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(appResponse)
+}
+
+func (h *UserHandler) GetMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := utils.GetUserIDFromContext(r.Context())
+	if !ok {
+		appError := error.NewAppError("User not found", http.StatusUnauthorized)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	currentUser, err := h.useCase.GetByID(userID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrUserNotFound) {
+			status = http.StatusNotFound
+		}
+		appError := error.NewAppError(err.Error(), status)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	appResponse := response.NewAppResponse(currentUser, "User fetched successfully", nil)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(appResponse)
+}
+
+func (h *UserHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := utils.GetUserIDFromContext(r.Context())
+	if !ok {
+		appError := error.NewAppError("User not found", http.StatusUnauthorized)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+	sessionID, _ := utils.GetSessionIDFromContext(r.Context())
+
+	var updateData UpdateProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&updateData); err != nil {
+		appError := error.NewAppError("Invalid input data", http.StatusBadRequest)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	updatedUser, err := h.useCase.UpdateProfile(userID, sessionID, updateData)
+	if err != nil {
+		var validation *ValidationError
+		if errors.As(err, &validation) {
+			appError := error.NewAppError("Invalid user data", http.StatusBadRequest).WithErrors(validation.Errors)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(appError.StatusCode)
+			json.NewEncoder(w).Encode(appError)
+			return
+		}
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrUserNotFound) {
+			status = http.StatusNotFound
+		}
+		appError := error.NewAppError(err.Error(), status)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	appResponse := response.NewAppResponse(updatedUser, "User updated successfully", nil)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(appResponse)
+}
+
+// ChangeMyPassword keeps the current session signed in and revokes the others.
+func (h *UserHandler) ChangeMyPassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := utils.GetUserIDFromContext(r.Context())
+	if !ok {
+		appError := error.NewAppError("User not found", http.StatusUnauthorized)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+	sessionID, _ := utils.GetSessionIDFromContext(r.Context())
+
+	var passwordData ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&passwordData); err != nil {
+		appError := error.NewAppError("Invalid input data", http.StatusBadRequest)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	err := h.useCase.ChangePassword(userID, sessionID, passwordData)
+	if err != nil {
+		var validation *ValidationError
+		if errors.As(err, &validation) {
+			appError := error.NewAppError("Invalid password data", http.StatusBadRequest).WithErrors(validation.Errors)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(appError.StatusCode)
+			json.NewEncoder(w).Encode(appError)
+			return
+		}
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrUserNotFound) {
+			status = http.StatusNotFound
+		}
+		appError := error.NewAppError(err.Error(), status)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appError.StatusCode)
+		json.NewEncoder(w).Encode(appError)
+		return
+	}
+
+	appResponse := response.NewAppResponse(nil, "Password changed successfully", nil)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(appResponse)
+}
@@ -0,0 +1,46 @@
+package user
+
+import (
+	"errors"
+	"time"
+)
+
+type TokenPurpose string
+
+const (
+	PasswordResetPurpose     TokenPurpose = "PASSWORD_RESET"
+	EmailVerificationPurpose TokenPurpose = "EMAIL_VERIFICATION"
+)
+
+var (
+	ErrInvalidUserToken     = errors.New("invalid or expired token")
+	ErrEmailNotVerified     = errors.New("email not verified")
+	ErrEmailAlreadyVerified = errors.New("email already verified")
+)
+
+// UserToken is a single-use token sent by email to reset a password or to
+// verify an address. Only its SHA-256 is stored.
+type UserToken struct {
+	ID        uint         `gorm:"primaryKey;autoIncrement"`
+	UserID    uint         `gorm:"not null;index"`
+	User      User         `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Purpose   TokenPurpose `gorm:"type:varchar(30);not null"`
+	TokenHash string       `gorm:"type:varchar(64);uniqueIndex;not null"`
+	ExpiresAt time.Time    `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+type UserTokenRepository interface {
+	// Create stores the token and discards the unused ones the user had for
+	// the same purpose, so only the latest email works.
+	Create(token *UserToken) error
+	// Consume marks the token as used and returns it, failing with
+	// ErrInvalidUserToken when it is unknown, expired or already used.
+	Consume(tokenHash string, purpose TokenPurpose) (*UserToken, error)
+	// ResetPassword consumes a password reset token and, in the same
+	// transaction, sets the new password, marks the email as verified and
+	// revokes every session of the user.
+	ResetPassword(tokenHash string, passwordHash string) error
+	DeleteExpired(before time.Time) (int64, error)
+}
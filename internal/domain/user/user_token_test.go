@@ -0,0 +1,141 @@
+package user_test
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/reinaldo-silva/savina-stock/internal/domain/user"
+	"github.com/reinaldo-silva/savina-stock/internal/infrastructure/mailer"
+	memory_mailer "github.com/reinaldo-silva/savina-stock/internal/infrastructure/mailer/memory"
+)
+
+var tokenLink = regexp.MustCompile(`token=(\S+)`)
+
+// waitForMail waits for the count-th message, as the use case sends mail in
+// the background, and returns the token of its link.
+func waitForMail(t *testing.T, m *memory_mailer.MemoryMailer, count int) (mailer.Message, string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for len(m.Messages()) < count {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d emails, want %d", len(m.Messages()), count)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	message := m.Messages()[count-1]
+	match := tokenLink.FindStringSubmatch(message.Body)
+	if match == nil {
+		t.Fatalf("no token link in %q", message.Body)
+	}
+	return message, match[1]
+}
+
+func TestPasswordResetFlow(t *testing.T) {
+	f := newUserUseCase(t)
+	u := createUser(t, f.repo, testEmail, user.ClientRole)
+	createSession(t, f.sessions, u.ID, "laptop")
+	createSession(t, f.sessions, u.ID, "phone")
+
+	if err := f.uc.RequestPasswordReset(testEmail); err != nil {
+		t.Fatal(err)
+	}
+	message, token := waitForMail(t, f.mailer, 1)
+	if message.To != testEmail {
+		t.Fatalf("email sent to %s, want %s", message.To, testEmail)
+	}
+
+	if err := f.uc.ResetPassword(token, "newsecret1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if active := f.sessions.ActiveSessions(u.ID); active != 0 {
+		t.Fatalf("%d sessions are still active after the reset", active)
+	}
+	stored, _ := f.repo.FindByID(u.ID)
+	if stored.EmailVerifiedAt == nil {
+		t.Fatal("the email was not marked as verified")
+	}
+	if _, _, err := f.uc.SignInUseCase(testEmail, "newsecret1", testIP); err != nil {
+		t.Fatalf("cannot sign in with the new password: %v", err)
+	}
+
+	if err := f.uc.ResetPassword(token, "othersecret1"); !errors.Is(err, user.ErrInvalidUserToken) {
+		t.Fatalf("reusing the link got %v, want ErrInvalidUserToken", err)
+	}
+}
+
+func TestPasswordResetWithWeakPasswordKeepsTheToken(t *testing.T) {
+	f := newUserUseCase(t)
+	createUser(t, f.repo, testEmail, user.ClientRole)
+
+	if err := f.uc.RequestPasswordReset(testEmail); err != nil {
+		t.Fatal(err)
+	}
+	_, token := waitForMail(t, f.mailer, 1)
+
+	var validation *user.ValidationError
+	if err := f.uc.ResetPassword(token, "short"); !errors.As(err, &validation) {
+		t.Fatalf("got %v, want a validation error", err)
+	}
+	if err := f.uc.ResetPassword(token, "newsecret1"); err != nil {
+		t.Fatalf("the link stopped working after a rejected password: %v", err)
+	}
+}
+
+func TestOnlyTheLatestPasswordResetLinkWorks(t *testing.T) {
+	f := newUserUseCase(t)
+	createUser(t, f.repo, testEmail, user.ClientRole)
+
+	if err := f.uc.RequestPasswordReset(testEmail); err != nil {
+		t.Fatal(err)
+	}
+	_, first := waitForMail(t, f.mailer, 1)
+	if err := f.uc.RequestPasswordReset(testEmail); err != nil {
+		t.Fatal(err)
+	}
+	_, second := waitForMail(t, f.mailer, 2)
+
+	if err := f.uc.ResetPassword(first, "newsecret1"); !errors.Is(err, user.ErrInvalidUserToken) {
+		t.Fatalf("the older link got %v, want ErrInvalidUserToken", err)
+	}
+	if err := f.uc.ResetPassword(second, "newsecret1"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPasswordResetForUnknownEmailSendsNothing(t *testing.T) {
+	f := newUserUseCase(t)
+
+	if err := f.uc.RequestPasswordReset("nobody@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if messages := f.mailer.Messages(); len(messages) != 0 {
+		t.Fatalf("sent %d emails for an unknown address", len(messages))
+	}
+}
+
+func TestEmailVerificationFlow(t *testing.T) {
+	f := newUserUseCase(t)
+
+	created, err := f.uc.SignUp(user.SignUpRequest{Name: "Ana", Email: testEmail, Password: testPassword})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, token := waitForMail(t, f.mailer, 1)
+
+	if err := f.uc.VerifyEmail(token); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := f.repo.FindByID(created.ID)
+	if stored.EmailVerifiedAt == nil {
+		t.Fatal("the email was not marked as verified")
+	}
+
+	if err := f.uc.VerifyEmail(token); !errors.Is(err, user.ErrInvalidUserToken) {
+		t.Fatalf("reusing the link got %v, want ErrInvalidUserToken", err)
+	}
+}
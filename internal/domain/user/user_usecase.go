@@ -1,36 +1,62 @@
 package user
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"strings"
+	"log"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/reinaldo-silva/savina-stock/config"
+	"github.com/reinaldo-silva/savina-stock/internal/infrastructure/mailer"
+	"github.com/reinaldo-silva/savina-stock/package/pagination"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type UserUseCase struct {
-	repo UserRepository
+	repo        UserRepository
+	sessionRepo SessionRepository
+	tokenRepo   UserTokenRepository
+	attempts    LoginAttemptStore
+	mailer      mailer.Implementation
 }
 
 type Claims struct {
-	UserID uint   `json:"user_id"`
-	Role   string `json:"role"`
+	UserID    uint   `json:"user_id"`
+	Role      string `json:"role"`
+	SessionID uint   `json:"sid"`
 	jwt.RegisteredClaims
 }
 
-func NewUserUseCase(repo UserRepository) *UserUseCase {
+func NewUserUseCase(
+	repo UserRepository,
+	sessionRepo SessionRepository,
+	tokenRepo UserTokenRepository,
+	attempts LoginAttemptStore,
+	mailer mailer.Implementation) *UserUseCase {
 	return &UserUseCase{
-		repo: repo,
+		repo:        repo,
+		sessionRepo: sessionRepo,
+		tokenRepo:   tokenRepo,
+		attempts:    attempts,
+		mailer:      mailer,
 	}
 }
 
-func (uc *UserUseCase) GetAll(page int, pageSize int) ([]UserResponse, int64, error) {
-	users, total, err := uc.repo.GetAll(page, pageSize)
+func (uc *UserUseCase) GetAll(params pagination.Params) ([]UserResponse, int64, pagination.Result, error) {
+	var users []User
+	var total int64
+	var result pagination.Result
+	var err error
+
+	if params.CursorMode {
+		users, total, result, err = uc.repo.GetAllByCursor(params.Cursor, params.PageSize)
+	} else {
+		users, total, err = uc.repo.GetAll(params.Page, params.PageSize)
+	}
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, result, err
 	}
 
 	var userResponses []UserResponse
@@ -38,21 +64,248 @@ func (uc *UserUseCase) GetAll(page int, pageSize int) ([]UserResponse, int64, er
 		userResponses = append(userResponses, *user.ToResponse())
 	}
 
-	return userResponses, total, nil
+	return userResponses, total, result, nil
+}
+
+// Create registers a user on behalf of an admin, with the same checks as the
+// public sign-up but any role.
+func (uc *UserUseCase) Create(req CreateUserRequest) (*UserResponse, error) {
+	req.Normalize()
+	if err := req.Validate(config.LoadConfig().PasswordPolicy); err != nil {
+		return nil, err
+	}
+
+	return uc.register(req.Name, req.Email, req.Password, req.Role)
+}
+
+func (uc *UserUseCase) GetByID(id uint) (*UserResponse, error) {
+	existingUser, err := uc.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return existingUser.ToResponse(), nil
+}
+
+// Update changes the name and email of a user. A new email has to be
+// verified again, so a verification link is sent to it.
+func (uc *UserUseCase) Update(id uint, req UpdateUserRequest) (*UserResponse, error) {
+	req.Normalize()
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	existingUser, err := uc.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	emailInUse := &ValidationError{}
+	emailInUse.add("email", ErrEmailInUse.Error())
+
+	emailChanged := NormalizeEmail(existingUser.Email) != req.Email
+	if emailChanged {
+		if other, err := uc.repo.FindByEmail(req.Email); err == nil && other != nil && other.ID != id {
+			return nil, emailInUse
+		}
+		existingUser.EmailVerifiedAt = nil
+	}
+
+	existingUser.Name = req.Name
+	existingUser.Email = req.Email
+	if err := uc.repo.Update(existingUser); err != nil {
+		if errors.Is(err, ErrEmailInUse) {
+			return nil, emailInUse
+		}
+		return nil, err
+	}
+
+	if emailChanged {
+		if err := uc.RequestEmailVerification(existingUser.Email); err != nil {
+			log.Printf("failed to request email verification for %s: %v", existingUser.Email, err)
+		}
+	}
+
+	return existingUser.ToResponse(), nil
+}
+
+// UpdateProfile is Update for the signed in user. An email change must be
+// confirmed with the current password and signs out the other sessions.
+func (uc *UserUseCase) UpdateProfile(userID uint, sessionID uint, req UpdateProfileRequest) (*UserResponse, error) {
+	existingUser, err := uc.repo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	emailChanged := NormalizeEmail(existingUser.Email) != NormalizeEmail(req.Email)
+	if emailChanged && bcrypt.CompareHashAndPassword([]byte(existingUser.Password), []byte(req.CurrentPassword)) != nil {
+		validation := &ValidationError{}
+		if req.CurrentPassword == "" {
+			validation.add("current_password", "current password is required to change the email")
+		} else {
+			validation.add("current_password", "current password is incorrect")
+		}
+		return nil, validation
+	}
+
+	updatedUser, err := uc.Update(userID, UpdateUserRequest{Name: req.Name, Email: req.Email})
+	if err != nil {
+		return nil, err
+	}
+
+	if emailChanged {
+		if err := uc.sessionRepo.RevokeOthers(userID, sessionID); err != nil {
+			return nil, err
+		}
+	}
+
+	return updatedUser, nil
+}
+
+// ChangeRole sets the role of a user and signs them out, since access
+// tokens carry the role they were issued with.
+func (uc *UserUseCase) ChangeRole(id uint, role Role) (*UserResponse, error) {
+	if !role.IsValid() {
+		return nil, ErrInvalidRole
+	}
+
+	existingUser, err := uc.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if existingUser.Role == role {
+		return existingUser.ToResponse(), nil
+	}
+
+	if err := uc.repo.UpdateRole(id, role); err != nil {
+		return nil, err
+	}
+
+	if err := uc.sessionRepo.RevokeAllForUser(id); err != nil {
+		return nil, err
+	}
+
+	existingUser.Role = role
+	return existingUser.ToResponse(), nil
+}
+
+// Deactivate blocks the sign-in of a user and revokes their sessions. The
+// acting admin cannot deactivate themself.
+func (uc *UserUseCase) Deactivate(actorID uint, id uint) (*UserResponse, error) {
+	if actorID == id {
+		return nil, ErrCannotDeactivateSelf
+	}
+
+	existingUser, err := uc.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !existingUser.Active {
+		return existingUser.ToResponse(), nil
+	}
+
+	if err := uc.repo.SetActive(id, false); err != nil {
+		return nil, err
+	}
+
+	if err := uc.sessionRepo.RevokeAllForUser(id); err != nil {
+		return nil, err
+	}
+
+	existingUser.Active = false
+	return existingUser.ToResponse(), nil
+}
+
+func (uc *UserUseCase) Activate(id uint) (*UserResponse, error) {
+	existingUser, err := uc.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.repo.SetActive(id, true); err != nil {
+		return nil, err
+	}
+
+	existingUser.Active = true
+	return existingUser.ToResponse(), nil
+}
+
+// ChangePassword replaces the password of the signed in user, who must
+// confirm the current one. The other sessions are signed out.
+func (uc *UserUseCase) ChangePassword(userID uint, sessionID uint, req ChangePasswordRequest) error {
+	existingUser, err := uc.repo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+
+	validation := &ValidationError{}
+	if bcrypt.CompareHashAndPassword([]byte(existingUser.Password), []byte(req.CurrentPassword)) != nil {
+		validation.add("current_password", "current password is incorrect")
+	}
+	for _, message := range validatePassword(config.LoadConfig().PasswordPolicy, req.NewPassword) {
+		validation.add("new_password", message)
+	}
+	if err := validation.orNil(); err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return errors.New("error generating password hash")
+	}
+
+	if err := uc.repo.UpdatePassword(userID, string(hashedPassword)); err != nil {
+		return err
+	}
+
+	return uc.sessionRepo.RevokeOthers(userID, sessionID)
 }
 
-func (uc *UserUseCase) Create(u User) (*User, error) {
+// SignUp registers a client from the public sign-up form and emails the
+// verification link.
+func (uc *UserUseCase) SignUp(req SignUpRequest) (*UserResponse, error) {
+	req.Normalize()
+	if err := req.Validate(config.LoadConfig().PasswordPolicy); err != nil {
+		return nil, err
+	}
 
-	if strings.TrimSpace(u.Name) == "" {
-		return nil, errors.New("user name cannot be empty")
+	return uc.register(req.Name, req.Email, req.Password, ClientRole)
+}
+
+// register saves a validated user and sends them the email verification link.
+func (uc *UserUseCase) register(name string, email string, password string, role Role) (*UserResponse, error) {
+	emailInUse := &ValidationError{}
+	emailInUse.add("email", ErrEmailInUse.Error())
+
+	if existingUser, err := uc.repo.FindByEmail(email); err == nil && existingUser != nil {
+		return nil, emailInUse
 	}
 
-	err := uc.repo.Create(u)
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
+		return nil, errors.New("error generating password hash")
+	}
+
+	newUser := User{
+		Name:     name,
+		Email:    email,
+		Password: string(hashedPassword),
+		Role:     role,
+	}
+	if err := uc.repo.Create(&newUser); err != nil {
+		if errors.Is(err, ErrEmailInUse) {
+			return nil, emailInUse
+		}
 		return nil, err
 	}
 
-	return &u, nil
+	if err := uc.RequestEmailVerification(newUser.Email); err != nil {
+		log.Printf("failed to request email verification for %s: %v", newUser.Email, err)
+	}
+
+	return newUser.ToResponse(), nil
 }
 
 func (uc *UserUseCase) GetByEmail(email string) (*UserResponse, error) {
@@ -69,39 +322,345 @@ func (uc *UserUseCase) GetByEmail(email string) (*UserResponse, error) {
 	return user.ToResponse(), err
 }
 
-func (uc *UserUseCase) SignInUseCase(email string, pass string) (*UserResponse, string, error) {
+// SignInUseCase checks the credentials of a sign-in coming from ip. Failures
+// are counted for both the account and the address, see LoginThrottle.
+//
+// Each attempt is counted as a failure before the password is checked and
+// forgiven once it succeeds. The count comes back from the same atomic write,
+// so a burst of parallel guesses cannot get past MaxFailures.
+func (uc *UserUseCase) SignInUseCase(email string, pass string, ip string) (*UserResponse, *TokenPair, error) {
+	throttle := loginThrottle(config.LoadConfig())
+	now := time.Now()
+
+	attemptKeys := map[string]int{
+		accountAttemptKey(email): throttle.MaxFailures,
+		ipAttemptKey(ip):         throttle.IPMaxFailures,
+	}
+	for key, maxFailures := range attemptKeys {
+		attempt, err := uc.attempts.Get(key)
+		if err != nil {
+			return nil, nil, err
+		}
+		if until, locked := throttle.blockedUntil(attempt, maxFailures); now.Before(until) {
+			return nil, nil, &LoginThrottledError{RetryAfter: until.Sub(now), Locked: locked}
+		}
+	}
+
+	for key, maxFailures := range attemptKeys {
+		attempt, err := uc.attempts.RecordFailure(key, now, now.Add(-throttle.Lockout))
+		if err != nil {
+			return nil, nil, err
+		}
+		if attempt.Failures > maxFailures {
+			return nil, nil, &LoginThrottledError{RetryAfter: throttle.Lockout, Locked: true}
+		}
+	}
 
 	existingUser, err := uc.repo.FindByEmail(email)
 	if err != nil {
-		return nil, "", errors.New("invalid email or password")
+		return nil, nil, errors.New("invalid email or password")
 	}
 
 	err = bcrypt.CompareHashAndPassword([]byte(existingUser.Password), []byte(pass))
 	if err != nil {
-		return nil, "", errors.New("invalid email or password")
+		return nil, nil, errors.New("invalid email or password")
+
+	}
+
+	// The account is cleared, but the address only gets this attempt back:
+	// clearing it would let anyone with an account of their own reset it
+	// between guesses.
+	if err := uc.attempts.Reset(accountAttemptKey(email)); err != nil {
+		return nil, nil, err
+	}
+	if err := uc.attempts.Forgive(ipAttemptKey(ip)); err != nil {
+		return nil, nil, err
+	}
+
+	if !existingUser.Active {
+		return nil, nil, ErrUserDeactivated
+	}
+
+	if config.LoadConfig().RequireEmailVerification && existingUser.EmailVerifiedAt == nil {
+		return nil, nil, ErrEmailNotVerified
+	}
+
+	tokens, err := uc.startSession(existingUser)
+	if err != nil {
+		return nil, nil, errors.New("error generating token")
+	}
+
+	return existingUser.ToResponse(), tokens, nil
+}
+
+// Refresh exchanges a refresh token for a new token pair. The presented token
+// is spent; using it again means it leaked, so the session is revoked.
+func (uc *UserUseCase) Refresh(refreshToken string) (*TokenPair, error) {
+	current, err := uc.sessionRepo.FindRefreshToken(HashToken(refreshToken))
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if current.UsedAt != nil {
+		if err := uc.sessionRepo.Revoke(current.SessionID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	now := time.Now()
+	if !current.Session.IsActive(now) || !now.Before(current.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := uc.repo.FindByID(current.Session.UserID)
+	if err != nil || !user.Active {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	token, tokenHash, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	next := &RefreshToken{
+		SessionID: current.SessionID,
+		TokenHash: tokenHash,
+		ExpiresAt: current.Session.ExpiresAt,
+	}
+	if err := uc.sessionRepo.Rotate(current, next); err != nil {
+		if errors.Is(err, ErrRefreshTokenReused) {
+			if revokeErr := uc.sessionRepo.Revoke(current.SessionID); revokeErr != nil {
+				return nil, revokeErr
+			}
+		}
+		return nil, err
+	}
+
+	accessToken, accessExpiresAt, err := uc.generateJWT(user, current.SessionID)
+	if err != nil {
+		return nil, errors.New("error generating token")
+	}
+
+	return &TokenPair{
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  accessExpiresAt,
+		RefreshToken:          token,
+		RefreshTokenExpiresAt: next.ExpiresAt,
+	}, nil
+}
+
+// SignOut revokes a single session, the one the access token belongs to.
+func (uc *UserUseCase) SignOut(sessionID uint) error {
+	return uc.sessionRepo.Revoke(sessionID)
+}
+
+// SignOutAll revokes every session of the user, on every device.
+func (uc *UserUseCase) SignOutAll(userID uint) error {
+	return uc.sessionRepo.RevokeAllForUser(userID)
+}
+
+// IsSessionActive is used by the JWT middleware to reject access tokens of
+// revoked sessions.
+func (uc *UserUseCase) IsSessionActive(sessionID uint) (bool, error) {
+	return uc.sessionRepo.IsActive(sessionID)
+}
+
+// PurgeExpiredSessions deletes the sessions that expired more than a day ago,
+// along with their refresh tokens.
+func (uc *UserUseCase) PurgeExpiredSessions(ctx context.Context) (int, error) {
+	deleted, err := uc.sessionRepo.DeleteExpired(time.Now().Add(-24 * time.Hour))
+	return int(deleted), err
+}
+
+// UnlockAccount clears the failed sign-ins of a user, lifting a lockout
+// before it expires.
+func (uc *UserUseCase) UnlockAccount(userID uint) error {
+	existingUser, err := uc.repo.FindByID(userID)
+	if err != nil {
+		return err
+	}
 
+	return uc.attempts.Reset(accountAttemptKey(existingUser.Email))
+}
+
+// PurgeStaleLoginAttempts deletes the failures old enough to be forgotten.
+func (uc *UserUseCase) PurgeStaleLoginAttempts(ctx context.Context) (int, error) {
+	throttle := loginThrottle(config.LoadConfig())
+	deleted, err := uc.attempts.DeleteStale(time.Now().Add(-throttle.Lockout))
+	return int(deleted), err
+}
+
+func loginThrottle(cfg *config.Config) LoginThrottle {
+	return LoginThrottle{
+		MaxFailures:   cfg.LoginMaxFailures,
+		IPMaxFailures: cfg.LoginIPMaxFailures,
+		BackoffBase:   time.Duration(cfg.LoginBackoffBaseSeconds) * time.Second,
+		Lockout:       time.Duration(cfg.LoginLockoutMinutes) * time.Minute,
 	}
+}
 
-	token, err := uc.generateJWT(existingUser)
+// RequestPasswordReset emails a reset link. Unknown addresses are ignored
+// without error, so the endpoint does not reveal who has an account.
+func (uc *UserUseCase) RequestPasswordReset(email string) error {
+	existingUser, err := uc.repo.FindByEmail(email)
 	if err != nil {
-		return nil, "", errors.New("error generating token")
+		return nil
+	}
+
+	cfg := config.LoadConfig()
+	token, err := uc.issueUserToken(existingUser, PasswordResetPurpose, time.Duration(cfg.PasswordResetTTLMinutes)*time.Minute)
+	if err != nil {
+		return err
+	}
+
+	uc.sendMail(mailer.Message{
+		To:      existingUser.Email,
+		Subject: "Redefinição de senha",
+		Body: fmt.Sprintf("Olá, %s.\n\nPara redefinir sua senha, acesse o link abaixo em até %d minuto(s):\n\n%s/reset-password?token=%s\n\nSe você não pediu a redefinição, ignore este email.",
+			existingUser.Name, cfg.PasswordResetTTLMinutes, cfg.AppURL, token),
+	})
+	return nil
+}
+
+// ResetPassword spends a reset token and replaces the password. Every session
+// of the user is signed out, and the address counts as verified since the
+// link was received there.
+func (uc *UserUseCase) ResetPassword(token string, newPassword string) error {
+	if err := ValidatePassword(config.LoadConfig().PasswordPolicy, newPassword); err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	return uc.tokenRepo.ResetPassword(HashToken(token), string(hashedPassword))
+}
+
+// RequestEmailVerification emails a verification link. Like the password
+// reset, unknown addresses are ignored; already verified ones too.
+func (uc *UserUseCase) RequestEmailVerification(email string) error {
+	existingUser, err := uc.repo.FindByEmail(email)
+	if err != nil || existingUser.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	cfg := config.LoadConfig()
+	token, err := uc.issueUserToken(existingUser, EmailVerificationPurpose, time.Duration(cfg.EmailVerificationTTLHours)*time.Hour)
+	if err != nil {
+		return err
+	}
+
+	uc.sendMail(mailer.Message{
+		To:      existingUser.Email,
+		Subject: "Confirme seu email",
+		Body: fmt.Sprintf("Olá, %s.\n\nPara confirmar seu email, acesse o link abaixo em até %d hora(s):\n\n%s/verify-email?token=%s",
+			existingUser.Name, cfg.EmailVerificationTTLHours, cfg.AppURL, token),
+	})
+	return nil
+}
+
+func (uc *UserUseCase) VerifyEmail(token string) error {
+	userToken, err := uc.tokenRepo.Consume(HashToken(token), EmailVerificationPurpose)
+	if err != nil {
+		return err
+	}
+
+	return uc.repo.MarkEmailVerified(userToken.UserID, time.Now())
+}
+
+// PurgeExpiredUserTokens deletes the reset and verification tokens that
+// expired more than a day ago.
+func (uc *UserUseCase) PurgeExpiredUserTokens(ctx context.Context) (int, error) {
+	deleted, err := uc.tokenRepo.DeleteExpired(time.Now().Add(-24 * time.Hour))
+	return int(deleted), err
+}
+
+func (uc *UserUseCase) issueUserToken(user *User, purpose TokenPurpose, ttl time.Duration) (string, error) {
+	token, tokenHash, err := newOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = uc.tokenRepo.Create(&UserToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// sendMail delivers in the background, so a slow mail server does not delay
+// the request nor tell apart the addresses that have an account.
+func (uc *UserUseCase) sendMail(message mailer.Message) {
+	if uc.mailer == nil {
+		return
+	}
+
+	go func() {
+		if err := uc.mailer.Send(message); err != nil {
+			log.Printf("failed to send email to %s: %v", message.To, err)
+		}
+	}()
+}
+
+func (uc *UserUseCase) startSession(user *User) (*TokenPair, error) {
+	cfg := config.LoadConfig()
+	now := time.Now()
+
+	token, tokenHash, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	session := &Session{
+		UserID:     user.ID,
+		ExpiresAt:  now.Add(time.Duration(cfg.RefreshTokenTTLDays) * 24 * time.Hour),
+		LastUsedAt: now,
+	}
+	refreshToken := &RefreshToken{
+		TokenHash: tokenHash,
+		ExpiresAt: session.ExpiresAt,
+	}
+	if err := uc.sessionRepo.Create(session, refreshToken); err != nil {
+		return nil, err
+	}
+
+	accessToken, accessExpiresAt, err := uc.generateJWT(user, session.ID)
+	if err != nil {
+		return nil, err
 	}
 
-	return existingUser.ToResponse(), token, nil
+	return &TokenPair{
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  accessExpiresAt,
+		RefreshToken:          token,
+		RefreshTokenExpiresAt: session.ExpiresAt,
+	}, nil
 }
 
-func (uc *UserUseCase) generateJWT(user *User) (string, error) {
+func (uc *UserUseCase) generateJWT(user *User, sessionID uint) (string, time.Time, error) {
 	cfg := config.LoadConfig()
+	expiresAt := time.Now().Add(time.Duration(cfg.AccessTokenTTLMinutes) * time.Minute)
 
 	claims := Claims{
-		UserID: user.ID,
-		Role:   string(user.Role),
+		UserID:    user.ID,
+		Role:      string(user.Role),
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(cfg.JwtSecret))
+	signed, err := token.SignedString([]byte(cfg.JwtSecret))
+	return signed, expiresAt, err
 }
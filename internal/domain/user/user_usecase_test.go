@@ -0,0 +1,190 @@
+package user_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/reinaldo-silva/savina-stock/internal/domain/user"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/user/usertest"
+	memory_login_attempt "github.com/reinaldo-silva/savina-stock/internal/infrastructure/login_attempt/memory"
+	memory_mailer "github.com/reinaldo-silva/savina-stock/internal/infrastructure/mailer/memory"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type userFixture struct {
+	uc       *user.UserUseCase
+	repo     *usertest.UserRepository
+	sessions *usertest.SessionRepository
+	mailer   *memory_mailer.MemoryMailer
+}
+
+func newUserUseCase(t *testing.T) userFixture {
+	t.Helper()
+	t.Setenv("PASSWORD_MIN_LENGTH", "8")
+	t.Setenv("PASSWORD_REQUIRE_LETTER", "true")
+	t.Setenv("PASSWORD_REQUIRE_DIGIT", "true")
+	t.Setenv("APP_URL", "http://shop.test")
+
+	f := userFixture{
+		repo:     usertest.NewUserRepository(),
+		sessions: usertest.NewSessionRepository(),
+		mailer:   memory_mailer.NewMemoryMailer(),
+	}
+	f.uc = user.NewUserUseCase(f.repo, f.sessions, usertest.NewUserTokenRepository(f.repo, f.sessions), memory_login_attempt.NewMemoryLoginAttemptStore(), f.mailer)
+	return f
+}
+
+func createUser(t *testing.T, repo *usertest.UserRepository, email string, role user.Role) *user.User {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u := &user.User{Name: "Test", Email: email, Password: string(hash), Role: role}
+	if err := repo.Create(u); err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func TestChangeRoleKeepsTheLastAdmin(t *testing.T) {
+	f := newUserUseCase(t)
+	admin := createUser(t, f.repo, "admin@example.com", user.AdminRole)
+
+	if _, err := f.uc.ChangeRole(admin.ID, user.ClientRole); !errors.Is(err, user.ErrLastAdmin) {
+		t.Fatalf("got %v, want ErrLastAdmin", err)
+	}
+}
+
+func TestConcurrentAdminDemotionsKeepOneAdmin(t *testing.T) {
+	f := newUserUseCase(t)
+	first := createUser(t, f.repo, "first@example.com", user.AdminRole)
+	second := createUser(t, f.repo, "second@example.com", user.AdminRole)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i, id := range []uint{first.ID, second.ID} {
+		wg.Add(1)
+		go func(i int, id uint) {
+			defer wg.Done()
+			if i == 0 {
+				_, errs[i] = f.uc.ChangeRole(id, user.ClientRole)
+			} else {
+				_, errs[i] = f.uc.Deactivate(first.ID, id)
+			}
+		}(i, id)
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, err := range errs {
+		if errors.Is(err, user.ErrLastAdmin) {
+			failed++
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if failed != 1 {
+		t.Fatalf("%d demotions were refused, want exactly 1", failed)
+	}
+}
+
+func createSession(t *testing.T, sessions *usertest.SessionRepository, userID uint, tokenHash string) *user.Session {
+	t.Helper()
+	session := &user.Session{UserID: userID, ExpiresAt: time.Now().Add(time.Hour)}
+	if err := sessions.Create(session, &user.RefreshToken{TokenHash: tokenHash}); err != nil {
+		t.Fatal(err)
+	}
+	return session
+}
+
+func TestUpdateProfileEmailChangeRequiresCurrentPassword(t *testing.T) {
+	f := newUserUseCase(t)
+	u := createUser(t, f.repo, testEmail, user.ClientRole)
+
+	for _, password := range []string{"", "wrong-password"} {
+		_, err := f.uc.UpdateProfile(u.ID, 0, user.UpdateProfileRequest{Name: "Ana", Email: "new@example.com", CurrentPassword: password})
+
+		var validation *user.ValidationError
+		if !errors.As(err, &validation) || len(validation.Errors) != 1 || validation.Errors[0].Field != "current_password" {
+			t.Fatalf("password %q: got %v, want a current_password error", password, err)
+		}
+	}
+
+	stored, _ := f.repo.FindByID(u.ID)
+	if stored.Email != testEmail {
+		t.Fatalf("email changed to %s without the current password", stored.Email)
+	}
+}
+
+func TestUpdateProfileEmailChangeRevokesOtherSessions(t *testing.T) {
+	f := newUserUseCase(t)
+	u := createUser(t, f.repo, testEmail, user.ClientRole)
+	current := createSession(t, f.sessions, u.ID, "current")
+	createSession(t, f.sessions, u.ID, "other")
+
+	updated, err := f.uc.UpdateProfile(u.ID, current.ID, user.UpdateProfileRequest{Name: "Ana", Email: "New@Example.com ", CurrentPassword: testPassword})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Email != "new@example.com" {
+		t.Fatalf("got email %s, want new@example.com", updated.Email)
+	}
+	if active := f.sessions.ActiveSessions(u.ID); active != 1 {
+		t.Fatalf("%d sessions are active, want only the current one", active)
+	}
+}
+
+func TestUpdateProfileNameChangeKeepsSessions(t *testing.T) {
+	f := newUserUseCase(t)
+	u := createUser(t, f.repo, testEmail, user.ClientRole)
+	current := createSession(t, f.sessions, u.ID, "current")
+	createSession(t, f.sessions, u.ID, "other")
+
+	if _, err := f.uc.UpdateProfile(u.ID, current.ID, user.UpdateProfileRequest{Name: "Ana Maria", Email: testEmail}); err != nil {
+		t.Fatal(err)
+	}
+	if active := f.sessions.ActiveSessions(u.ID); active != 2 {
+		t.Fatalf("%d sessions are active, want 2", active)
+	}
+}
+
+func TestCreateValidatesLikeSignUp(t *testing.T) {
+	f := newUserUseCase(t)
+
+	_, err := f.uc.Create(user.CreateUserRequest{Name: "A", Email: "not-an-email", Password: "123", Role: "OWNER"})
+
+	var validation *user.ValidationError
+	if !errors.As(err, &validation) {
+		t.Fatalf("got %v, want a validation error", err)
+	}
+	fields := map[string]bool{}
+	for _, fieldError := range validation.Errors {
+		fields[fieldError.Field] = true
+	}
+	for _, field := range []string{"name", "email", "password", "role"} {
+		if !fields[field] {
+			t.Errorf("missing error for %s in %v", field, validation.Errors)
+		}
+	}
+}
+
+func TestCreateHashesThePassword(t *testing.T) {
+	f := newUserUseCase(t)
+
+	created, err := f.uc.Create(user.CreateUserRequest{Name: "Ana", Email: " Ana@Example.com", Password: testPassword})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Role != user.ClientRole || created.Email != testEmail {
+		t.Fatalf("got %s %s, want a client with a normalized email", created.Role, created.Email)
+	}
+
+	stored, _ := f.repo.FindByID(created.ID)
+	if bcrypt.CompareHashAndPassword([]byte(stored.Password), []byte(testPassword)) != nil {
+		t.Fatal("the stored password is not a hash of the given one")
+	}
+}
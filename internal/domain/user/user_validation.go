@@ -0,0 +1,225 @@
+package user
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/reinaldo-silva/savina-stock/config"
+)
+
+const (
+	MinNameLength  = 2
+	MaxNameLength  = 100
+	MaxEmailLength = 150
+)
+
+// SignUpRequest is the body accepted by the public sign-up. It has no role:
+// self-registered users are always clients.
+type SignUpRequest struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// CreateUserRequest is the body of POST /users, where an admin registers a
+// user with any role. An empty role creates a client.
+type CreateUserRequest struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Role     Role   `json:"role"`
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every invalid field of a request, so the client can
+// show them all at once.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, fieldError := range e.Errors {
+		messages = append(messages, fmt.Sprintf("%s: %s", fieldError.Field, fieldError.Message))
+	}
+	return "invalid fields: " + strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) add(field string, message string) {
+	e.Errors = append(e.Errors, FieldError{Field: field, Message: message})
+}
+
+func (e *ValidationError) orNil() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+// NormalizeEmail trims and lowercases an address, the form emails are stored
+// and looked up in.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Normalize trims the name and normalizes the email in place.
+func (req *SignUpRequest) Normalize() {
+	req.Name = strings.TrimSpace(req.Name)
+	req.Email = NormalizeEmail(req.Email)
+}
+
+func (req SignUpRequest) Validate(policy config.PasswordPolicy) error {
+	validation := &ValidationError{}
+
+	validateName(validation, req.Name)
+	validateEmail(validation, req.Email)
+	for _, message := range validatePassword(policy, req.Password) {
+		validation.add("password", message)
+	}
+
+	return validation.orNil()
+}
+
+func (req *CreateUserRequest) Normalize() {
+	req.Name = strings.TrimSpace(req.Name)
+	req.Email = NormalizeEmail(req.Email)
+	if req.Role == "" {
+		req.Role = ClientRole
+	}
+}
+
+func (req CreateUserRequest) Validate(policy config.PasswordPolicy) error {
+	validation := &ValidationError{}
+
+	validateName(validation, req.Name)
+	validateEmail(validation, req.Email)
+	for _, message := range validatePassword(policy, req.Password) {
+		validation.add("password", message)
+	}
+	if !req.Role.IsValid() {
+		validation.add("role", ErrInvalidRole.Error())
+	}
+
+	return validation.orNil()
+}
+
+// UpdateUserRequest is the body of the profile updates, by an admin or by the
+// user themself. Role and password have their own endpoints.
+type UpdateUserRequest struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+func (req *UpdateUserRequest) Normalize() {
+	req.Name = strings.TrimSpace(req.Name)
+	req.Email = NormalizeEmail(req.Email)
+}
+
+func (req UpdateUserRequest) Validate() error {
+	validation := &ValidationError{}
+
+	validateName(validation, req.Name)
+	validateEmail(validation, req.Email)
+
+	return validation.orNil()
+}
+
+// UpdateProfileRequest is the body of PUT /me. Changing the email requires the
+// current password, as the email is what a password reset is sent to.
+type UpdateProfileRequest struct {
+	Name            string `json:"name"`
+	Email           string `json:"email"`
+	CurrentPassword string `json:"current_password"`
+}
+
+// ChangePasswordRequest is the body of POST /me/password.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// ValidatePassword checks a new password against the policy, as done on
+// sign-up and password reset.
+func ValidatePassword(policy config.PasswordPolicy, password string) error {
+	validation := &ValidationError{}
+	for _, message := range validatePassword(policy, password) {
+		validation.add("password", message)
+	}
+	return validation.orNil()
+}
+
+func validateName(validation *ValidationError, name string) {
+	length := utf8.RuneCountInString(name)
+	switch {
+	case length == 0:
+		validation.add("name", "name is required")
+	case length < MinNameLength || length > MaxNameLength:
+		validation.add("name", fmt.Sprintf("name must have between %d and %d characters", MinNameLength, MaxNameLength))
+	}
+}
+
+func validateEmail(validation *ValidationError, email string) {
+	if email == "" {
+		validation.add("email", "email is required")
+		return
+	}
+
+	if len(email) > MaxEmailLength {
+		validation.add("email", fmt.Sprintf("email must have at most %d characters", MaxEmailLength))
+		return
+	}
+
+	// ParseAddress also accepts "Name <address>", which is not an email.
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email || !strings.Contains(email[strings.LastIndex(email, "@")+1:], ".") {
+		validation.add("email", "email is invalid")
+	}
+}
+
+func validatePassword(policy config.PasswordPolicy, password string) []string {
+	var messages []string
+
+	if utf8.RuneCountInString(password) < policy.MinLength {
+		messages = append(messages, fmt.Sprintf("password must have at least %d characters", policy.MinLength))
+	}
+
+	// bcrypt ignores everything past 72 bytes.
+	if len(password) > 72 {
+		messages = append(messages, "password must have at most 72 bytes")
+	}
+
+	var hasLetter, hasUpper, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+			hasUpper = hasUpper || unicode.IsUpper(r)
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+
+	if policy.RequireLetter && !hasLetter {
+		messages = append(messages, "password must contain a letter")
+	}
+	if policy.RequireUppercase && !hasUpper {
+		messages = append(messages, "password must contain an uppercase letter")
+	}
+	if policy.RequireDigit && !hasDigit {
+		messages = append(messages, "password must contain a digit")
+	}
+	if policy.RequireSymbol && !hasSymbol {
+		messages = append(messages, "password must contain a symbol")
+	}
+
+	return messages
+}
@@ -0,0 +1,345 @@
+// Package usertest provides in-memory implementations of the user
+// repositories for tests.
+package usertest
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/reinaldo-silva/savina-stock/internal/domain/user"
+	"github.com/reinaldo-silva/savina-stock/package/pagination"
+)
+
+type UserRepository struct {
+	mu     sync.Mutex
+	nextID uint
+	users  map[uint]*user.User
+}
+
+func NewUserRepository() *UserRepository {
+	return &UserRepository{users: make(map[uint]*user.User)}
+}
+
+func (r *UserRepository) GetAll(page int, pageSize int) ([]user.User, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := make([]int, 0, len(r.users))
+	for id := range r.users {
+		ids = append(ids, int(id))
+	}
+	sort.Ints(ids)
+
+	var users []user.User
+	for i, id := range ids {
+		if i >= (page-1)*pageSize && len(users) < pageSize {
+			users = append(users, *r.users[uint(id)])
+		}
+	}
+	return users, int64(len(ids)), nil
+}
+
+func (r *UserRepository) GetAllByCursor(cursor *pagination.Cursor, pageSize int) ([]user.User, int64, pagination.Result, error) {
+	users, total, err := r.GetAll(1, pageSize)
+	return users, total, pagination.Result{}, err
+}
+
+func (r *UserRepository) Create(u *user.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.users {
+		if strings.EqualFold(existing.Email, u.Email) {
+			return user.ErrEmailInUse
+		}
+	}
+
+	r.nextID++
+	u.ID = r.nextID
+	if u.Role == "" {
+		u.Role = user.ClientRole
+	}
+	// Mirrors the column default.
+	u.Active = true
+	stored := *u
+	r.users[u.ID] = &stored
+	return nil
+}
+
+func (r *UserRepository) FindByEmail(email string) (*user.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.users {
+		if strings.EqualFold(existing.Email, strings.TrimSpace(email)) {
+			found := *existing
+			return &found, nil
+		}
+	}
+	return nil, user.ErrUserNotFound
+}
+
+func (r *UserRepository) FindByID(id uint) (*user.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.users[id]
+	if !ok {
+		return nil, user.ErrUserNotFound
+	}
+	found := *existing
+	return &found, nil
+}
+
+func (r *UserRepository) UpdatePassword(id uint, passwordHash string) error {
+	return r.update(id, func(u *user.User) { u.Password = passwordHash })
+}
+
+func (r *UserRepository) MarkEmailVerified(id uint, verifiedAt time.Time) error {
+	return r.update(id, func(u *user.User) {
+		if u.EmailVerifiedAt == nil {
+			u.EmailVerifiedAt = &verifiedAt
+		}
+	})
+}
+
+func (r *UserRepository) Update(u *user.User) error {
+	r.mu.Lock()
+	for _, existing := range r.users {
+		if existing.ID != u.ID && strings.EqualFold(existing.Email, u.Email) {
+			r.mu.Unlock()
+			return user.ErrEmailInUse
+		}
+	}
+	r.mu.Unlock()
+
+	return r.update(u.ID, func(stored *user.User) {
+		stored.Name = u.Name
+		stored.Email = u.Email
+		stored.EmailVerifiedAt = u.EmailVerifiedAt
+	})
+}
+
+func (r *UserRepository) UpdateRole(id uint, role user.Role) error {
+	return r.updateGuarded(id, role != user.AdminRole, func(u *user.User) { u.Role = role })
+}
+
+func (r *UserRepository) SetActive(id uint, active bool) error {
+	return r.updateGuarded(id, !active, func(u *user.User) { u.Active = active })
+}
+
+func (r *UserRepository) update(id uint, change func(u *user.User)) error {
+	return r.updateGuarded(id, false, change)
+}
+
+// updateGuarded applies change under the lock, refusing it when demotes is
+// set and the user is the only active admin.
+func (r *UserRepository) updateGuarded(id uint, demotes bool, change func(u *user.User)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.users[id]
+	if !ok {
+		return user.ErrUserNotFound
+	}
+
+	if demotes && existing.Role == user.AdminRole && existing.Active {
+		admins := 0
+		for _, u := range r.users {
+			if u.Role == user.AdminRole && u.Active {
+				admins++
+			}
+		}
+		if admins <= 1 {
+			return user.ErrLastAdmin
+		}
+	}
+
+	change(existing)
+	return nil
+}
+
+type SessionRepository struct {
+	mu       sync.Mutex
+	nextID   uint
+	sessions map[uint]*user.Session
+	tokens   map[string]*user.RefreshToken
+}
+
+func NewSessionRepository() *SessionRepository {
+	return &SessionRepository{
+		sessions: make(map[uint]*user.Session),
+		tokens:   make(map[string]*user.RefreshToken),
+	}
+}
+
+func (r *SessionRepository) Create(session *user.Session, token *user.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	session.ID = r.nextID
+	stored := *session
+	r.sessions[session.ID] = &stored
+
+	token.SessionID = session.ID
+	storedToken := *token
+	r.tokens[token.TokenHash] = &storedToken
+	return nil
+}
+
+func (r *SessionRepository) FindRefreshToken(tokenHash string) (*user.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[tokenHash]
+	if !ok {
+		return nil, user.ErrInvalidRefreshToken
+	}
+	found := *token
+	found.Session = *r.sessions[token.SessionID]
+	return &found, nil
+}
+
+func (r *SessionRepository) Rotate(used *user.RefreshToken, next *user.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := r.tokens[used.TokenHash]
+	if stored.UsedAt != nil {
+		return user.ErrRefreshTokenReused
+	}
+	now := time.Now()
+	stored.UsedAt = &now
+
+	storedNext := *next
+	r.tokens[next.TokenHash] = &storedNext
+	return nil
+}
+
+func (r *SessionRepository) Revoke(sessionID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.revoke(func(s *user.Session) bool { return s.ID == sessionID })
+	return nil
+}
+
+func (r *SessionRepository) RevokeAllForUser(userID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.revoke(func(s *user.Session) bool { return s.UserID == userID })
+	return nil
+}
+
+func (r *SessionRepository) RevokeOthers(userID uint, keepSessionID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.revoke(func(s *user.Session) bool { return s.UserID == userID && s.ID != keepSessionID })
+	return nil
+}
+
+func (r *SessionRepository) IsActive(sessionID uint) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[sessionID]
+	return ok && session.IsActive(time.Now()), nil
+}
+
+func (r *SessionRepository) DeleteExpired(before time.Time) (int64, error) {
+	return 0, nil
+}
+
+// ActiveSessions counts the sessions of the user that were not revoked.
+func (r *SessionRepository) ActiveSessions(userID uint) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int
+	for _, s := range r.sessions {
+		if s.UserID == userID && s.IsActive(time.Now()) {
+			count++
+		}
+	}
+	return count
+}
+
+func (r *SessionRepository) revoke(match func(s *user.Session) bool) {
+	now := time.Now()
+	for _, s := range r.sessions {
+		if match(s) && s.RevokedAt == nil {
+			s.RevokedAt = &now
+		}
+	}
+}
+
+// UserTokenRepository resets passwords on the given user and session
+// repositories.
+type UserTokenRepository struct {
+	mu       sync.Mutex
+	tokens   map[string]*user.UserToken
+	users    *UserRepository
+	sessions *SessionRepository
+}
+
+func NewUserTokenRepository(users *UserRepository, sessions *SessionRepository) *UserTokenRepository {
+	return &UserTokenRepository{
+		tokens:   make(map[string]*user.UserToken),
+		users:    users,
+		sessions: sessions,
+	}
+}
+
+func (r *UserTokenRepository) Create(token *user.UserToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for hash, existing := range r.tokens {
+		if existing.UserID == token.UserID && existing.Purpose == token.Purpose && existing.UsedAt == nil {
+			delete(r.tokens, hash)
+		}
+	}
+
+	stored := *token
+	r.tokens[token.TokenHash] = &stored
+	return nil
+}
+
+func (r *UserTokenRepository) Consume(tokenHash string, purpose user.TokenPurpose) (*user.UserToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[tokenHash]
+	now := time.Now()
+	if !ok || token.Purpose != purpose || token.UsedAt != nil || !now.Before(token.ExpiresAt) {
+		return nil, user.ErrInvalidUserToken
+	}
+
+	token.UsedAt = &now
+	consumed := *token
+	return &consumed, nil
+}
+
+func (r *UserTokenRepository) ResetPassword(tokenHash string, passwordHash string) error {
+	token, err := r.Consume(tokenHash, user.PasswordResetPurpose)
+	if err != nil {
+		return err
+	}
+
+	if err := r.users.UpdatePassword(token.UserID, passwordHash); err != nil {
+		return err
+	}
+	if err := r.users.MarkEmailVerified(token.UserID, time.Now()); err != nil {
+		return err
+	}
+	return r.sessions.RevokeAllForUser(token.UserID)
+}
+
+func (r *UserTokenRepository) DeleteExpired(before time.Time) (int64, error) {
+	return 0, nil
+}
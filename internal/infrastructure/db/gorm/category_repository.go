@@ -1,6 +1,7 @@
 package gorm
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/reinaldo-silva/savina-stock/internal/domain/category"
@@ -21,7 +22,7 @@ func (repo *GormCategoryRepository) Create(category *category.Category) error {
 
 func (repo *GormCategoryRepository) GetAll() ([]category.Category, error) {
 	var categories []category.Category
-	err := repo.db.Order("id ASC").Find(&categories).Error
+	err := repo.db.Order("position ASC").Order("id ASC").Find(&categories).Error
 	if err != nil {
 		return nil, err
 	}
@@ -29,6 +30,95 @@ func (repo *GormCategoryRepository) GetAll() ([]category.Category, error) {
 	return categories, nil
 }
 
+// GetAllWithProductCount also fills ProductCount with the number of available
+// products directly linked to each category.
+func (repo *GormCategoryRepository) GetAllWithProductCount() ([]category.Category, error) {
+	var categories []category.Category
+	err := repo.db.Model(&category.Category{}).
+		Select("categories.*, (?) AS product_count", repo.db.Table("product_categories pc").
+			Select("COUNT(*)").
+			Joins("JOIN products p ON p.id = pc.product_id").
+			Where("pc.category_id = categories.id AND p.available = ? AND p.deleted_at IS NULL", true).
+			Where("(p.available_from IS NULL OR p.available_from <= now())").
+			Where("(p.available_until IS NULL OR p.available_until > now())")).
+		Order("position ASC").Order("id ASC").
+		Find(&categories).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+func (repo *GormCategoryRepository) GetBySlug(slug string) (*category.Category, error) {
+	var c category.Category
+	if err := repo.db.Where("slug = ?", slug).First(&c).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, category.ErrCategoryNotFound
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (repo *GormCategoryRepository) UniqueSlug(base string, categoryID uint, reserved map[string]bool) (string, error) {
+	var taken []string
+	if err := repo.db.Model(&category.Category{}).
+		Where("(slug = ? OR slug LIKE ?) AND id <> ?", base, base+"-%", categoryID).
+		Pluck("slug", &taken).Error; err != nil {
+		return "", err
+	}
+
+	used := make(map[string]bool, len(taken))
+	for _, slug := range taken {
+		used[slug] = true
+	}
+
+	slug := base
+	for suffix := 2; used[slug] || reserved[slug]; suffix++ {
+		slug = fmt.Sprintf("%s-%d", base, suffix)
+	}
+
+	return slug, nil
+}
+
+func (repo *GormCategoryRepository) Reorder(ids []uint) error {
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		for position, id := range ids {
+			result := tx.Model(&category.Category{}).Where("id = ?", id).Update("position", position)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return category.ErrCategoryNotFound
+			}
+		}
+		return nil
+	})
+}
+
+// migrateCategorySlugs gives a slug to the categories created before slugs
+// existed.
+func migrateCategorySlugs(db *gorm.DB) error {
+	var categories []category.Category
+	if err := db.Where("slug IS NULL OR slug = ''").Order("id ASC").Find(&categories).Error; err != nil {
+		return err
+	}
+
+	repo := &GormCategoryRepository{db: db}
+	for _, c := range categories {
+		slug, err := repo.UniqueSlug(category.GenerateSlug(c.Name), c.ID, category.ReservedSlugs)
+		if err != nil {
+			return err
+		}
+		if err := db.Model(&category.Category{}).Where("id = ?", c.ID).Update("slug", slug).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (repo *GormCategoryRepository) GetByID(id uint) (*category.Category, error) {
 	var category category.Category
 	if err := repo.db.First(&category, id).Error; err != nil {
@@ -41,7 +131,12 @@ func (repo *GormCategoryRepository) GetByID(id uint) (*category.Category, error)
 }
 
 func (repo *GormCategoryRepository) Update(category *category.Category) error {
-	return repo.db.Save(category).Error
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(category).Error; err != nil {
+			return err
+		}
+		return refreshCategorySearchVectors(tx, category.ID)
+	})
 }
 
 func (repo *GormCategoryRepository) Delete(id uint) error {
@@ -54,6 +149,51 @@ func (repo *GormCategoryRepository) Delete(id uint) error {
 	return nil
 }
 
+func (repo *GormCategoryRepository) HasChildren(categoryID uint) (bool, error) {
+	var count int64
+	err := repo.db.Model(&category.Category{}).Where("parent_id = ?", categoryID).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// GetDescendantIDs lists every category below categoryID, at any depth.
+func (repo *GormCategoryRepository) GetDescendantIDs(categoryID uint) ([]uint, error) {
+	var ids []uint
+	err := repo.db.Raw(categorySubtreeQuery+" SELECT id FROM subtree WHERE id <> ?", []uint{categoryID}, categoryID).
+		Scan(&ids).Error
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// categorySubtreeQuery walks down from the given category ids. UNION (not
+// UNION ALL) stops the recursion should a cycle ever reach the table.
+const categorySubtreeQuery = `WITH RECURSIVE subtree AS (
+	SELECT id FROM categories WHERE id IN ?
+	UNION
+	SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id
+)`
+
+// categoryAncestorsQuery pairs every category with itself and each of its
+// ancestors, so whatever is linked to a category also counts for the
+// categories above it.
+const categoryAncestorsQuery = `WITH RECURSIVE ancestors AS (
+	SELECT id AS category_id, id AS ancestor_id FROM categories
+	UNION
+	SELECT a.category_id, c.parent_id FROM ancestors a
+	JOIN categories c ON c.id = a.ancestor_id
+	WHERE c.parent_id IS NOT NULL
+)`
+
+// categorySubtree is a subquery selecting the given categories and all of
+// their descendants.
+func categorySubtree(db *gorm.DB, ids []uint) *gorm.DB {
+	return db.Raw(categorySubtreeQuery+" SELECT id FROM subtree", ids)
+}
+
 func (repo *GormCategoryRepository) HasProducts(categoryID uint) (bool, error) {
 	var count int64
 	err := repo.db.Table("product_categories").Where("category_id = ?", categoryID).Count(&count).Error
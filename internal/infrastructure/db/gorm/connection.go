@@ -4,34 +4,54 @@ import (
 	"log"
 
 	"github.com/reinaldo-silva/savina-stock/internal/domain/category"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/inventory_count"
 	"github.com/reinaldo-silva/savina-stock/internal/domain/product"
 	"github.com/reinaldo-silva/savina-stock/internal/domain/product_audit"
 	"github.com/reinaldo-silva/savina-stock/internal/domain/product_image"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/product_variant"
 	"github.com/reinaldo-silva/savina-stock/internal/domain/sale"
 	"github.com/reinaldo-silva/savina-stock/internal/domain/sale_item"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/stock_movement"
 	"github.com/reinaldo-silva/savina-stock/internal/domain/user"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
 func NewGormDB(dsn string) *gorm.DB {
-	connection, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	connection, err := gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
 		log.Fatal("failed to connect database: ", err)
 	}
 
 	err = connection.AutoMigrate(
 		&product.Product{},
+		&product.ProductSlugAlias{},
+		&product_variant.ProductVariant{},
 		&product_image.ProductImage{},
 		&category.Category{},
 		&user.User{},
+		&user.Session{},
+		&user.RefreshToken{},
+		&user.UserToken{},
+		&user.LoginAttempt{},
 		&product_audit.ProductAudit{},
 		&sale.Sale{},
-		&sale_item.SaleItem{})
+		&sale_item.SaleItem{},
+		&stock_movement.StockMovement{},
+		&inventory_count.InventoryCount{},
+		&inventory_count.InventoryCountItem{})
 	if err != nil {
 		log.Fatal("failed to migrate database: ", err)
 	}
 
+	if err := migrateCategorySlugs(connection); err != nil {
+		log.Fatal("failed to generate category slugs: ", err)
+	}
+
+	if err := migrateProductSearch(connection); err != nil {
+		log.Fatal("failed to set up product search: ", err)
+	}
+
 	return connection
 
 }
@@ -1,9 +1,11 @@
 package gorm
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/reinaldo-silva/savina-stock/internal/domain/product"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/product_audit"
 	"github.com/reinaldo-silva/savina-stock/internal/domain/product_image"
 	"gorm.io/gorm"
 )
@@ -16,19 +18,26 @@ func NewGormImageRepository(db *gorm.DB) product_image.ImageRepository {
 	return &GormImageRepository{db: db}
 }
 
-func (r *GormImageRepository) CreateManyImages(productID uint, imageURLs []product_image.UploadedImage) error {
-	for _, url := range imageURLs {
-		image := product_image.ProductImage{
-			ProductID: productID,
-			ImageURL:  url.URL,
-			PublicID:  url.PublicID,
-			IsCover:   false,
+func (r *GormImageRepository) CreateManyImages(ctx context.Context, productID uint, imageURLs []product_image.UploadedImage) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+
+		var publicIDs []string
+		for _, url := range imageURLs {
+			image := product_image.ProductImage{
+				ProductID: productID,
+				ImageURL:  url.URL,
+				PublicID:  url.PublicID,
+				IsCover:   false,
+			}
+			if err := tx.Create(&image).Error; err != nil {
+				return err
+			}
+			publicIDs = append(publicIDs, url.PublicID)
 		}
-		if err := r.db.Create(&image).Error; err != nil {
-			return err
-		}
-	}
-	return nil
+
+		newValue := map[string]interface{}{"images": publicIDs}
+		return recordProductAudit(tx, productID, product_audit.ImagesAddedAction, nil, newValue, "Product images uploaded")
+	})
 }
 
 func (r *GormImageRepository) FindByProductID(productID uint) ([]product_image.ProductImage, error) {
@@ -42,7 +51,7 @@ func (r *GormImageRepository) FindByProductID(productID uint) ([]product_image.P
 func (r *GormImageRepository) FindImageByPublicIdAndProductSlug(publicID string, slug string) (*product_image.ProductImage, error) {
 	var image product_image.ProductImage
 
-	if err := r.db.Joins("JOIN products ON products.id = product_images.product_id").
+	if err := r.db.Joins("JOIN products ON products.id = product_images.product_id AND products.deleted_at IS NULL").
 		Where("product_images.public_id = ? AND products.slug = ?", publicID, slug).
 		First(&image).Error; err != nil {
 		return nil, err
@@ -66,26 +75,58 @@ func (r *GormImageRepository) DeleteByProductID(productID uint) error {
 	return nil
 }
 
-func (r *GormImageRepository) DeleteImage(uuid string) error {
-	return r.db.Where("public_id = ?", uuid).Delete(&product_image.ProductImage{}).Error
+func (r *GormImageRepository) DeleteImage(ctx context.Context, uuid string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+
+		var image product_image.ProductImage
+		if err := tx.Where("public_id = ?", uuid).First(&image).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Delete(&image).Error; err != nil {
+			return err
+		}
+
+		oldValue := map[string]interface{}{"image": image.PublicID, "is_cover": image.IsCover}
+		return recordProductAudit(tx, image.ProductID, product_audit.ImageRemovedAction, oldValue, nil, "Product image removed")
+	})
 }
 
-func (r *GormImageRepository) ResetCover(slug string) error {
+// SetImageAsCover resets the current cover of the product and marks the
+// given image as the new one in a single transaction.
+func (r *GormImageRepository) SetImageAsCover(ctx context.Context, slug string, uuid string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 
-	var productID uint
-	if err := r.db.Model(&product.Product{}).Where("slug = ?", slug).Select("id").Scan(&productID).Error; err != nil {
-		return fmt.Errorf("erro ao buscar produto pelo slug %s: %v", slug, err)
-	}
+		var productID uint
+		if err := tx.Model(&product.Product{}).Where("slug = ?", slug).Select("id").Scan(&productID).Error; err != nil {
+			return fmt.Errorf("erro ao buscar produto pelo slug %s: %v", slug, err)
+		}
 
-	if productID == 0 {
-		return fmt.Errorf("produto com slug %s não encontrado", slug)
-	}
+		if productID == 0 {
+			return fmt.Errorf("produto com slug %s não encontrado", slug)
+		}
 
-	return r.db.Model(&product_image.ProductImage{}).
-		Where("product_id = ?", productID).
-		Update("is_cover", false).Error
-}
+		var previousCovers []string
+		if err := tx.Model(&product_image.ProductImage{}).
+			Where("product_id = ? AND is_cover = ?", productID, true).
+			Pluck("public_id", &previousCovers).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&product_image.ProductImage{}).
+			Where("product_id = ?", productID).
+			Update("is_cover", false).Error; err != nil {
+			return fmt.Errorf("erro ao resetar imagem de capa anterior: %v", err)
+		}
+
+		if err := tx.Model(&product_image.ProductImage{}).
+			Where("public_id = ? AND product_id = ?", uuid, productID).
+			Update("is_cover", true).Error; err != nil {
+			return err
+		}
 
-func (r *GormImageRepository) SetImageAsCover(uuid string) error {
-	return r.db.Model(&product_image.ProductImage{}).Where("public_id = ?", uuid).Update("is_cover", true).Error
+		oldValue := map[string]interface{}{"cover": previousCovers}
+		newValue := map[string]interface{}{"cover": []string{uuid}}
+		return recordProductAudit(tx, productID, product_audit.CoverChangedAction, oldValue, newValue, "Product cover changed")
+	})
 }
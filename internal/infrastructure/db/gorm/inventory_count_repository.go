@@ -0,0 +1,224 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/reinaldo-silva/savina-stock/internal/domain/inventory_count"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/product"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/stock_movement"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GormInventoryCountRepository struct {
+	db *gorm.DB
+}
+
+func NewGormInventoryCountRepository(db *gorm.DB) inventory_count.InventoryCountRepository {
+	return &GormInventoryCountRepository{db: db}
+}
+
+func (r *GormInventoryCountRepository) GetAll(ctx context.Context, page int, pageSize int) ([]inventory_count.InventoryCount, int64, error) {
+	var counts []inventory_count.InventoryCount
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&inventory_count.InventoryCount{})
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.Order("created_at DESC").Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&counts).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return counts, total, nil
+}
+
+func (r *GormInventoryCountRepository) FindByID(id uint) (*inventory_count.InventoryCount, error) {
+	var count inventory_count.InventoryCount
+	if err := r.db.Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	}).First(&count, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, inventory_count.ErrInventoryCountNotFound
+		}
+		return nil, err
+	}
+	return &count, nil
+}
+
+func (r *GormInventoryCountRepository) Create(ctx context.Context, count *inventory_count.InventoryCount) error {
+	return r.db.WithContext(ctx).Omit("Items").Create(count).Error
+}
+
+func (r *GormInventoryCountRepository) SaveItems(ctx context.Context, countID uint, items []inventory_count.InventoryCountItem) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+
+		if _, err := lockOpenInventoryCount(tx, countID); err != nil {
+			return err
+		}
+
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "inventory_count_id"}, {Name: "product_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"counted_quantity", "updated_at"}),
+		}).Create(&items).Error
+	})
+}
+
+func (r *GormInventoryCountRepository) GetVariance(ctx context.Context, countID uint) ([]inventory_count.VarianceLine, error) {
+	var lines []inventory_count.VarianceLine
+
+	err := r.db.WithContext(ctx).Table("inventory_count_items ici").
+		Select(`ici.product_id,
+			p.slug,
+			p.name,
+			p.stock AS expected_quantity,
+			ici.counted_quantity,
+			ici.counted_quantity - p.stock AS variance`).
+		Joins("JOIN products p ON p.id = ici.product_id AND p.deleted_at IS NULL").
+		Where("ici.inventory_count_id = ?", countID).
+		Order("ici.id ASC").
+		Scan(&lines).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return lines, nil
+}
+
+// Commit sets every counted product's stock to the counted quantity and
+// records the difference as an adjustment movement tied to the session.
+func (r *GormInventoryCountRepository) Commit(ctx context.Context, countID uint) (*inventory_count.InventoryCount, error) {
+	var count *inventory_count.InventoryCount
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+
+		var err error
+		count, err = lockOpenInventoryCount(tx, countID)
+		if err != nil {
+			return err
+		}
+
+		var items []inventory_count.InventoryCountItem
+		if err := tx.Where("inventory_count_id = ?", countID).Order("id ASC").Find(&items).Error; err != nil {
+			return err
+		}
+
+		if len(items) == 0 {
+			return inventory_count.ErrInventoryCountEmpty
+		}
+
+		movement := stock_movement.StockMovement{
+			Type:      stock_movement.AdjustmentMovement,
+			Reason:    "Contagem de inventário",
+			Reference: fmt.Sprintf("inventory_count:%d", countID),
+		}
+
+		for i := range items {
+			item := &items[i]
+
+			// Products moved to the trash after being counted are left as they are,
+			// matching GetVariance, which no longer lists them.
+			var p product.Product
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&p, item.ProductID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					continue
+				}
+				return err
+			}
+
+			// A variant may have been added since the count was submitted. The
+			// product row is locked, as it is when a variant is created, so the
+			// check holds until the stock is written.
+			hasVariants, err := productHasVariants(tx, p.ID)
+			if err != nil {
+				return err
+			}
+			if hasVariants {
+				return fmt.Errorf("product %s has variants and cannot be counted as a whole: %w", p.Slug, product.ErrProductHasVariants)
+			}
+
+			expected := p.Stock
+			variance := item.CountedQuantity - expected
+			item.ExpectedQuantity = &expected
+			item.Variance = &variance
+
+			if err := tx.Model(item).Updates(map[string]interface{}{
+				"expected_quantity": expected,
+				"variance":          variance,
+			}).Error; err != nil {
+				return err
+			}
+
+			if variance == 0 {
+				continue
+			}
+
+			if err := tx.Model(&p).Update("stock", item.CountedQuantity).Error; err != nil {
+				return err
+			}
+
+			if err := recordStockMovement(tx, movement, p.ID, variance, item.CountedQuantity); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		count.Status = inventory_count.CommittedStatus
+		count.CommittedAt = &now
+		count.Items = items
+
+		return tx.Model(count).Updates(map[string]interface{}{
+			"status":       count.Status,
+			"committed_at": count.CommittedAt,
+		}).Error
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return count, nil
+}
+
+func (r *GormInventoryCountRepository) Cancel(ctx context.Context, countID uint) (*inventory_count.InventoryCount, error) {
+	var count *inventory_count.InventoryCount
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+
+		var err error
+		count, err = lockOpenInventoryCount(tx, countID)
+		if err != nil {
+			return err
+		}
+
+		count.Status = inventory_count.CanceledStatus
+		return tx.Model(count).Update("status", count.Status).Error
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return count, nil
+}
+
+func lockOpenInventoryCount(tx *gorm.DB, countID uint) (*inventory_count.InventoryCount, error) {
+	var count inventory_count.InventoryCount
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&count, countID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, inventory_count.ErrInventoryCountNotFound
+		}
+		return nil, err
+	}
+
+	if count.Status != inventory_count.OpenStatus {
+		return nil, inventory_count.ErrInventoryCountNotOpen
+	}
+
+	return &count, nil
+}
@@ -0,0 +1,49 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/reinaldo-silva/savina-stock/internal/domain/inventory_count"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/product"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/product_variant"
+)
+
+func TestCommitRefusesProductsThatGotVariants(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewGormInventoryCountRepository(db)
+	p := createTestProduct(t, db, 4)
+
+	count := &inventory_count.InventoryCount{}
+	if err := repo.Create(context.Background(), count); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Where("inventory_count_id = ?", count.ID).Delete(&inventory_count.InventoryCountItem{})
+		db.Delete(&inventory_count.InventoryCount{}, count.ID)
+	})
+
+	items := []inventory_count.InventoryCountItem{{InventoryCountID: count.ID, ProductID: p.ID, CountedQuantity: 7}}
+	if err := repo.SaveItems(context.Background(), count.ID, items); err != nil {
+		t.Fatal(err)
+	}
+
+	// The variant arrives after the count was submitted.
+	variant := &product_variant.ProductVariant{ProductID: p.ID, SKU: p.Slug, Stock: 4, Available: true}
+	if err := db.Create(variant).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.Commit(context.Background(), count.ID); !errors.Is(err, product.ErrProductHasVariants) {
+		t.Fatalf("got %v, want ErrProductHasVariants", err)
+	}
+
+	var stored product.Product
+	if err := db.First(&stored, p.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Stock != 4 {
+		t.Fatalf("stock was overwritten with %d, want 4", stored.Stock)
+	}
+}
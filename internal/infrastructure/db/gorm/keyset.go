@@ -0,0 +1,100 @@
+package gorm
+
+import (
+	"github.com/reinaldo-silva/savina-stock/package/pagination"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// keyset describes the ordering of a cursor paginated listing: an optional
+// sort column, ascending or descending as Desc says, followed by the id as an
+// ascending tie breaker.
+type keyset struct {
+	Key       string
+	Column    string
+	Desc      bool
+	IDColumn  string
+	SortValue interface{}
+}
+
+// findKeysetPage loads the page after (or before) cursor. One extra row is
+// fetched to know whether there is a page beyond this one.
+func findKeysetPage[T any](
+	query *gorm.DB,
+	k keyset,
+	cursor *pagination.Cursor,
+	pageSize int,
+	cursorOf func(item T) (string, uint)) ([]T, pagination.Result, error) {
+	var items []T
+	var result pagination.Result
+
+	backward := cursor != nil && cursor.Backward
+
+	if cursor != nil {
+		idOperator := ">"
+		if backward {
+			idOperator = "<"
+		}
+
+		if k.Column == "" {
+			query = query.Where(k.IDColumn+" "+idOperator+" ?", cursor.ID)
+		} else {
+			sortOperator := ">"
+			if k.Desc != backward {
+				sortOperator = "<"
+			}
+			query = query.Where(
+				"("+k.Column+" "+sortOperator+" ? OR ("+k.Column+" = ? AND "+k.IDColumn+" "+idOperator+" ?))",
+				k.SortValue, k.SortValue, cursor.ID)
+		}
+	}
+
+	if k.Column != "" {
+		query = query.Order(clause.OrderByColumn{Column: clause.Column{Name: k.Column, Raw: true}, Desc: k.Desc != backward})
+	}
+	query = query.Order(clause.OrderByColumn{Column: clause.Column{Name: k.IDColumn, Raw: true}, Desc: backward})
+
+	if err := query.Limit(pageSize + 1).Find(&items).Error; err != nil {
+		return nil, result, err
+	}
+
+	hasMore := len(items) > pageSize
+	if hasMore {
+		items = items[:pageSize]
+	}
+
+	if backward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	if len(items) == 0 {
+		return items, result, nil
+	}
+
+	newCursor := func(item T, backward bool) string {
+		value, id := cursorOf(item)
+		return pagination.Encode(pagination.Cursor{Key: k.Key, Value: value, ID: id, Backward: backward})
+	}
+
+	first, last := items[0], items[len(items)-1]
+
+	// Going forward there is a previous page whenever a cursor was given;
+	// going backward there is always a next page, the one we came from.
+	if backward {
+		result.NextCursor = newCursor(last, false)
+		if hasMore {
+			result.PrevCursor = newCursor(first, true)
+		}
+	} else {
+		if hasMore {
+			result.NextCursor = newCursor(last, false)
+		}
+		if cursor != nil {
+			result.PrevCursor = newCursor(first, true)
+		}
+	}
+
+	return items, result, nil
+}
@@ -0,0 +1,60 @@
+package gorm
+
+import (
+	"errors"
+	"time"
+
+	"github.com/reinaldo-silva/savina-stock/internal/domain/user"
+	"gorm.io/gorm"
+)
+
+type GormLoginAttemptStore struct {
+	db *gorm.DB
+}
+
+func NewGormLoginAttemptStore(db *gorm.DB) user.LoginAttemptStore {
+	return &GormLoginAttemptStore{db: db}
+}
+
+func (s *GormLoginAttemptStore) Get(key string) (*user.LoginAttempt, error) {
+	var attempt user.LoginAttempt
+	if err := s.db.Where("key = ?", key).First(&attempt).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+// RecordFailure counts the failure with a single upsert, so concurrent
+// attempts against the same key are all counted.
+func (s *GormLoginAttemptStore) RecordFailure(key string, now time.Time, resetBefore time.Time) (*user.LoginAttempt, error) {
+	var attempt user.LoginAttempt
+	err := s.db.Raw(`
+		INSERT INTO login_attempts (key, failures, last_failure_at) VALUES (?, 1, ?)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN login_attempts.last_failure_at < ? THEN 1 ELSE login_attempts.failures + 1 END,
+			last_failure_at = EXCLUDED.last_failure_at
+		RETURNING key, failures, last_failure_at`, key, now, resetBefore).
+		Scan(&attempt).Error
+	if err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+func (s *GormLoginAttemptStore) Forgive(key string) error {
+	return s.db.Model(&user.LoginAttempt{}).
+		Where("key = ? AND failures > 0", key).
+		Update("failures", gorm.Expr("failures - 1")).Error
+}
+
+func (s *GormLoginAttemptStore) Reset(key string) error {
+	return s.db.Where("key = ?", key).Delete(&user.LoginAttempt{}).Error
+}
+
+func (s *GormLoginAttemptStore) DeleteStale(before time.Time) (int64, error) {
+	result := s.db.Where("last_failure_at < ?", before).Delete(&user.LoginAttempt{})
+	return result.RowsAffected, result.Error
+}
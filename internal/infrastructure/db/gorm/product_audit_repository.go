@@ -0,0 +1,101 @@
+package gorm
+
+import (
+	"context"
+
+	"github.com/reinaldo-silva/savina-stock/internal/domain/product"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/product_audit"
+	"github.com/reinaldo-silva/savina-stock/utils"
+	"gorm.io/gorm"
+)
+
+type GormProductAuditRepository struct {
+	db *gorm.DB
+}
+
+func NewGormProductAuditRepository(db *gorm.DB) product_audit.ProductAuditRepository {
+	return &GormProductAuditRepository{db: db}
+}
+
+func (r *GormProductAuditRepository) GetByProductID(
+	ctx context.Context,
+	productID uint,
+	page int,
+	pageSize int) ([]product_audit.ProductAudit, int64, error) {
+	var audits []product_audit.ProductAudit
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&product_audit.ProductAudit{}).Where("product_id = ?", productID)
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.Order("created_at DESC").Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&audits).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return audits, total, nil
+}
+
+// recordProductAudit stores an audit entry inside the caller's transaction.
+// The acting user comes from the context attached with db.WithContext.
+func recordProductAudit(tx *gorm.DB, productID uint, action string, oldValue, newValue interface{}, description string) error {
+	audit := product_audit.ProductAudit{
+		ProductID:   productID,
+		Action:      action,
+		Description: description,
+	}
+
+	if oldValue != nil {
+		audit.OldValue = utils.ToJSON(oldValue)
+	}
+	if newValue != nil {
+		audit.NewValue = utils.ToJSON(newValue)
+	}
+
+	if userID, err := utils.GetCurrentUserID(tx); err == nil {
+		audit.UserID = userID
+	}
+
+	return tx.Create(&audit).Error
+}
+
+func productAuditSnapshot(p product.Product) map[string]interface{} {
+	categoryIDs := make([]uint, 0, len(p.Categories))
+	for _, c := range p.Categories {
+		categoryIDs = append(categoryIDs, c.ID)
+	}
+
+	return map[string]interface{}{
+		"name":            p.Name,
+		"slug":            p.Slug,
+		"sku":             p.SKU,
+		"barcode":         p.Barcode,
+		"description":     p.Description,
+		"price":           p.Price,
+		"cost":            p.Cost,
+		"stock":           p.Stock,
+		"min_stock":       p.MinStock,
+		"available":       p.Available,
+		"available_from":  p.AvailableFrom,
+		"available_until": p.AvailableUntil,
+		"category_ids":    categoryIDs,
+	}
+}
+
+// diffSnapshots keeps only the keys whose values changed between both
+// snapshots. Callers skip the audit when nothing changed.
+func diffSnapshots(oldSnapshot, newSnapshot map[string]interface{}) (map[string]interface{}, map[string]interface{}) {
+	oldValue := make(map[string]interface{})
+	newValue := make(map[string]interface{})
+
+	for key, value := range newSnapshot {
+		if utils.ToJSON(oldSnapshot[key]) != utils.ToJSON(value) {
+			oldValue[key] = oldSnapshot[key]
+			newValue[key] = value
+		}
+	}
+
+	return oldValue, newValue
+}
@@ -0,0 +1,109 @@
+package gorm
+
+import (
+	"context"
+	"database/sql/driver"
+	"strconv"
+	"strings"
+
+	"github.com/reinaldo-silva/savina-stock/internal/domain/product"
+	"gorm.io/gorm"
+)
+
+// GetFacets counts the products matching the filters, and the text query when
+// one is given, per category, per stock availability and per price bucket. A
+// category counts the products of its whole subtree, as filtering by it does.
+func (r *GormProductRepository) GetFacets(
+	ctx context.Context,
+	query string,
+	filter product.ProductFilter,
+	priceBounds []float64) (*product.ProductFacets, error) {
+	matching := func() *gorm.DB {
+		base := r.applyProductFilter(r.db.WithContext(ctx).Model(&product.Product{}), filter)
+		if query != "" {
+			base = base.Where("search_vector @@ "+searchQueryExpression, query)
+		}
+		return base
+	}
+
+	facets := &product.ProductFacets{}
+
+	err := r.db.WithContext(ctx).Raw(categoryAncestorsQuery+`
+		SELECT c.id, c.name, c.slug, COUNT(DISTINCT pc.product_id) AS count
+		FROM product_categories pc
+		JOIN ancestors a ON a.category_id = pc.category_id
+		JOIN categories c ON c.id = a.ancestor_id
+		WHERE pc.product_id IN (?)
+		GROUP BY c.id, c.name, c.slug
+		ORDER BY count DESC, c.name ASC`, matching().Select("products.id")).
+		Scan(&facets.Categories).Error
+	if err != nil {
+		return nil, err
+	}
+	if facets.Categories == nil {
+		facets.Categories = []product.CategoryFacet{}
+	}
+
+	err = matching().
+		Select("COUNT(*) FILTER (WHERE products.stock > 0) AS in_stock, " +
+			"COUNT(*) FILTER (WHERE products.stock <= 0) AS out_of_stock").
+		Scan(&facets.Availability).Error
+	if err != nil {
+		return nil, err
+	}
+
+	facets.PriceBuckets, err = r.priceBucketFacets(matching(), priceBounds)
+	if err != nil {
+		return nil, err
+	}
+
+	return facets, nil
+}
+
+// priceBucketFacets groups the products with width_bucket over the thresholds
+// 0, bounds...; bucket i holds the prices in [thresholds[i-1], thresholds[i]).
+func (r *GormProductRepository) priceBucketFacets(query *gorm.DB, bounds []float64) ([]product.PriceBucketFacet, error) {
+	thresholds := append(numericArray{0}, bounds...)
+
+	var rows []struct {
+		Bucket int
+		Count  int64
+	}
+	err := query.
+		Select("width_bucket(products.price, ?::numeric[]) AS bucket, COUNT(*) AS count", thresholds).
+		Group("bucket").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := make([]product.PriceBucketFacet, len(bounds)+1)
+	for i := range buckets {
+		if i > 0 {
+			buckets[i].Min = bounds[i-1]
+		}
+		if i < len(bounds) {
+			buckets[i].Max = &bounds[i]
+		}
+	}
+
+	for _, row := range rows {
+		if row.Bucket >= 1 && row.Bucket <= len(buckets) {
+			buckets[row.Bucket-1].Count += row.Count
+		}
+	}
+
+	return buckets, nil
+}
+
+// numericArray binds a list of numbers as a single PostgreSQL array
+// parameter. GORM expands a plain slice into a parenthesized list instead.
+type numericArray []float64
+
+func (a numericArray) Value() (driver.Value, error) {
+	values := make([]string, len(a))
+	for i, value := range a {
+		values[i] = strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return "{" + strings.Join(values, ",") + "}", nil
+}
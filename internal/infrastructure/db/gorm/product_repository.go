@@ -3,12 +3,25 @@ package gorm
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/reinaldo-silva/savina-stock/internal/domain/category"
 	"github.com/reinaldo-silva/savina-stock/internal/domain/product"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/product_audit"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/product_image"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/product_variant"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/stock_movement"
+	"github.com/reinaldo-silva/savina-stock/package/barcode"
+	"github.com/reinaldo-silva/savina-stock/package/pagination"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+const exportBatchSize = 500
+
 type GormProductRepository struct {
 	db *gorm.DB
 }
@@ -21,92 +34,607 @@ func (r *GormProductRepository) GetAll(
 	ctx context.Context,
 	page int,
 	pageSize int,
-	nameFilter string,
-	categoryIDs []uint,
-	onlyAvailable bool) ([]product.Product, int64, error) {
+	filter product.ProductFilter) ([]product.Product, int64, error) {
 	var products []product.Product
 	var total int64
 
-	query := r.db.WithContext(ctx).Preload("Images").Preload("Categories")
+	query := r.applyProductFilter(r.db.WithContext(ctx).Model(&product.Product{}), filter)
 
-	if nameFilter != "" {
-		query = query.Where("name ILIKE ?", "%"+nameFilter+"%")
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
 	}
 
-	if onlyAvailable {
-		query = query.Where("available = ?", true)
+	query = orderProducts(query.Preload("Images").Preload("Variants").Preload("Categories"), filter)
+	if err := query.Offset((page - 1) * pageSize).Limit(pageSize).Find(&products).Error; err != nil {
+		return nil, 0, err
 	}
 
-	if len(categoryIDs) > 0 {
-		query = query.Joins("JOIN product_categories pc ON pc.product_id = products.id").
-			Where("pc.category_id IN ?", categoryIDs)
+	return products, total, nil
+}
+
+// GetAllByCursor is the keyset paginated version of GetAll. The cursor keeps
+// the sort value and id of the row the page starts after.
+func (r *GormProductRepository) GetAllByCursor(
+	ctx context.Context,
+	cursor *pagination.Cursor,
+	pageSize int,
+	filter product.ProductFilter) ([]product.Product, int64, pagination.Result, error) {
+	var total int64
+
+	k := keyset{
+		Key:      fmt.Sprintf("products:%s:%t", filter.SortBy, filter.SortDesc),
+		Column:   "products.stock",
+		Desc:     true,
+		IDColumn: "products.id",
+	}
+	if column, ok := productSortColumns[filter.SortBy]; ok {
+		k.Column = column
+		k.Desc = filter.SortDesc
 	}
 
-	if err := query.Model(&product.Product{}).Count(&total).Error; err != nil {
-		return nil, 0, err
+	if cursor != nil {
+		if cursor.Key != k.Key {
+			return nil, 0, pagination.Result{}, pagination.ErrInvalidCursor
+		}
+
+		value, err := parseProductSortValue(filter.SortBy, cursor.Value)
+		if err != nil {
+			return nil, 0, pagination.Result{}, pagination.ErrInvalidCursor
+		}
+		k.SortValue = value
 	}
 
-	if err := query.Order("stock DESC").Order("id ASC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&products).Error; err != nil {
-		return nil, 0, err
+	query := r.applyProductFilter(r.db.WithContext(ctx).Model(&product.Product{}), filter)
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, pagination.Result{}, err
 	}
 
-	return products, total, nil
+	query = query.Preload("Images").Preload("Variants").Preload("Categories")
+	products, result, err := findKeysetPage(query, k, cursor, pageSize, func(p product.Product) (string, uint) {
+		return productSortValue(filter.SortBy, p), p.ID
+	})
+	if err != nil {
+		return nil, 0, pagination.Result{}, err
+	}
+
+	return products, total, result, nil
+}
+
+func productSortValue(sortBy product.SortField, p product.Product) string {
+	switch sortBy {
+	case product.SortByPrice:
+		return strconv.FormatFloat(p.Price, 'f', -1, 64)
+	case product.SortByName:
+		return p.Name
+	case product.SortByCreatedAt:
+		return p.CreatedAt.Format(time.RFC3339Nano)
+	}
+	return strconv.Itoa(p.Stock)
 }
 
-func (r *GormProductRepository) Create(p product.Product) error {
-	return r.db.Create(&p).Error
+func parseProductSortValue(sortBy product.SortField, value string) (interface{}, error) {
+	switch sortBy {
+	case product.SortByPrice:
+		return strconv.ParseFloat(value, 64)
+	case product.SortByName:
+		return value, nil
+	case product.SortByCreatedAt:
+		return time.Parse(time.RFC3339Nano, value)
+	}
+	return strconv.Atoi(value)
+}
+
+// StreamAll hands every product matching the filters to fn, in batches of
+// exportBatchSize ordered by id.
+func (r *GormProductRepository) StreamAll(
+	ctx context.Context,
+	filter product.ProductFilter,
+	fn func(products []product.Product) error) error {
+	var products []product.Product
+
+	query := r.applyProductFilter(r.db.WithContext(ctx), filter).Preload("Images").Preload("Categories")
+
+	return query.FindInBatches(&products, exportBatchSize, func(tx *gorm.DB, batch int) error {
+		return fn(products)
+	}).Error
+}
+
+func (r *GormProductRepository) applyProductFilter(query *gorm.DB, filter product.ProductFilter) *gorm.DB {
+	if filter.Name != "" {
+		query = query.Where("products.name ILIKE ?", "%"+filter.Name+"%")
+	}
+
+	if filter.OnlyAvailable {
+		query = query.Where("products.available = ?", true).
+			Where("(products.available_from IS NULL OR products.available_from <= now())").
+			Where("(products.available_until IS NULL OR products.available_until > now())")
+	} else if filter.Available != nil {
+		query = query.Where("products.available = ?", *filter.Available)
+	}
+
+	// Matching through subqueries keeps one row per product, so a product in
+	// several of the categories is neither duplicated nor counted twice. A
+	// category also matches the products of its subcategories.
+	if len(filter.CategoryIDs) > 0 {
+		if filter.CategoryMatch == product.MatchAllCategories {
+			for _, id := range filter.CategoryIDs {
+				query = query.Where("products.id IN (?)", r.productsInCategories([]uint{id}))
+			}
+		} else {
+			query = query.Where("products.id IN (?)", r.productsInCategories(filter.CategoryIDs))
+		}
+	}
+
+	if filter.MinPrice != nil {
+		query = query.Where("products.price >= ?", *filter.MinPrice)
+	}
+
+	if filter.MaxPrice != nil {
+		query = query.Where("products.price <= ?", *filter.MaxPrice)
+	}
+
+	if filter.InStock {
+		query = query.Where("products.stock > 0")
+	}
+
+	if filter.UpdatedSince != nil {
+		query = query.Where("products.updated_at >= ?", *filter.UpdatedSince)
+	}
+
+	return query
+}
+
+func (r *GormProductRepository) productsInCategories(categoryIDs []uint) *gorm.DB {
+	return r.db.Table("product_categories").Select("product_id").
+		Where("category_id IN (?)", categorySubtree(r.db, categoryIDs))
+}
+
+// productSortColumns maps the accepted sort fields to columns, so the value
+// reaching the ORDER BY clause never comes from the request.
+var productSortColumns = map[product.SortField]string{
+	product.SortByPrice:     "products.price",
+	product.SortByName:      "products.name",
+	product.SortByCreatedAt: "products.created_at",
+	product.SortByStock:     "products.stock",
+}
+
+func orderProducts(query *gorm.DB, filter product.ProductFilter) *gorm.DB {
+	column, ok := productSortColumns[filter.SortBy]
+	if !ok {
+		return query.Order("products.stock DESC").Order("products.id ASC")
+	}
+
+	return query.Order(clause.OrderByColumn{Column: clause.Column{Name: column, Raw: true}, Desc: filter.SortDesc}).
+		Order("products.id ASC")
+}
+
+func (r *GormProductRepository) Create(ctx context.Context, p *product.Product) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return createProduct(tx, p)
+	})
+
+	return r.translateProductError(err, 0, p.Slug)
+}
+
+// CreateMany saves imported products all at once, creating the categories
+// they reference that do not exist yet. Slugs for those categories are picked
+// inside the transaction so they see the ones created before them.
+func (r *GormProductRepository) CreateMany(ctx context.Context, products []*product.Product, newCategories []category.Category) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+
+		categoryRepo := &GormCategoryRepository{db: tx}
+		createdCategories := make(map[string]category.Category, len(newCategories))
+		for _, c := range newCategories {
+			slug, err := categoryRepo.UniqueSlug(category.GenerateSlug(c.Name), 0, category.ReservedSlugs)
+			if err != nil {
+				return err
+			}
+			c.Slug = slug
+			if err := tx.Create(&c).Error; err != nil {
+				return err
+			}
+			createdCategories[strings.ToLower(c.Name)] = c
+		}
+
+		for _, p := range products {
+			for i, c := range p.Categories {
+				if c.ID == 0 {
+					p.Categories[i] = createdCategories[strings.ToLower(c.Name)]
+				}
+			}
+
+			if err := createProduct(tx, p); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	slugs := make([]string, 0, len(products))
+	for _, p := range products {
+		slugs = append(slugs, p.Slug)
+	}
+	return r.translateProductError(err, 0, slugs...)
+}
+
+func createProduct(tx *gorm.DB, p *product.Product) error {
+	if err := ensureCodesFree(tx, productCodes(*p), 0, 0); err != nil {
+		return err
+	}
+
+	p.DeletedAt = gorm.DeletedAt{}
+	if err := tx.Omit("Variants").Create(p).Error; err != nil {
+		return err
+	}
+
+	if err := refreshProductSearchVector(tx, p.ID); err != nil {
+		return err
+	}
+
+	if p.Stock != 0 {
+		movement := stock_movement.StockMovement{
+			Type:   stock_movement.AdjustmentMovement,
+			Reason: "Estoque inicial",
+		}
+		if err := recordStockMovement(tx, movement, p.ID, p.Stock, p.Stock); err != nil {
+			return err
+		}
+	}
+
+	return recordProductAudit(tx, p.ID, product_audit.CreatedAction, nil, productAuditSnapshot(*p), "Product created")
+}
+
+// productCodes lists the codes set on p.
+func productCodes(p product.Product) []string {
+	var codes []string
+	if p.SKU != nil {
+		codes = append(codes, *p.SKU)
+	}
+	if p.Barcode != nil {
+		codes = append(codes, *p.Barcode)
+	}
+	return codes
 }
 
 func (r *GormProductRepository) FindBySlug(slug string) (*product.Product, error) {
 	var product product.Product
-	result := r.db.Where("slug = ?", slug).Preload("Images").Preload("Categories").First(&product)
+	result := r.db.Where("slug = ?", slug).Preload("Images").Preload("Variants", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	}).Preload("Categories").First(&product)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 	return &product, nil
 }
 
-func (r *GormProductRepository) DeleteBySlug(productID uint) error {
+// FindSlugAlias returns the current slug of the product that used to be
+// published under the given slug.
+func (r *GormProductRepository) FindSlugAlias(slug string) (string, error) {
+	var currentSlug string
+	err := r.db.Model(&product.Product{}).
+		Joins("JOIN product_slug_aliases psa ON psa.product_id = products.id").
+		Where("psa.slug = ?", slug).
+		Limit(1).
+		Pluck("products.slug", &currentSlug).Error
+	if err != nil {
+		return "", err
+	}
+
+	if currentSlug == "" {
+		return "", product.ErrProductNotFound
+	}
+
+	return currentSlug, nil
+}
+
+// UniqueSlug returns base, or base with the first free numeric suffix, skipping
+// slugs and aliases owned by other products as well as the reserved ones.
+func (r *GormProductRepository) UniqueSlug(base string, productID uint, reserved map[string]bool) (string, error) {
+	var taken []string
+	if err := r.db.Unscoped().Model(&product.Product{}).
+		Where("(slug = ? OR slug LIKE ?) AND id <> ?", base, base+"-%", productID).
+		Pluck("slug", &taken).Error; err != nil {
+		return "", err
+	}
+
+	var aliases []string
+	if err := r.db.Model(&product.ProductSlugAlias{}).
+		Where("(slug = ? OR slug LIKE ?) AND product_id <> ?", base, base+"-%", productID).
+		Pluck("slug", &aliases).Error; err != nil {
+		return "", err
+	}
+
+	used := make(map[string]bool, len(taken)+len(aliases))
+	for _, slug := range append(taken, aliases...) {
+		used[slug] = true
+	}
+
+	slug := base
+	for suffix := 2; used[slug] || reserved[slug]; suffix++ {
+		slug = fmt.Sprintf("%s-%d", base, suffix)
+	}
+
+	return slug, nil
+}
+
+// FindByCode looks a product up by any of the given codes, matching its
+// barcode, its SKU or the SKU of one of its variants. Product codes are
+// matched first, so a code left on both by older data resolves the same way
+// every time.
+func (r *GormProductRepository) FindByCode(codes []string) (*product.Product, error) {
+	query := func() *gorm.DB {
+		return r.db.Preload("Images").Preload("Variants", func(db *gorm.DB) *gorm.DB {
+			return db.Order("id ASC")
+		}).Preload("Categories")
+	}
+
+	var p product.Product
+	err := query().Where("barcode IN ? OR sku IN ?", codes, codes).First(&p).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		variantProducts := r.db.Model(&product_variant.ProductVariant{}).Select("product_id").Where("sku IN ?", codes)
+		err = query().Where("id IN (?)", variantProducts).First(&p).Error
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, product.ErrProductNotFound
+		}
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// CodeInUse tells whether any of the codes is taken by a product or a
+// variant. Unlike FindByCode it includes the trash, since trashed products
+// still hold their codes.
+func (r *GormProductRepository) CodeInUse(codes []string) (bool, error) {
+	return codesTaken(r.db, codes, 0, 0)
+}
+
+// productCodesLock is the advisory lock held while product and variant codes
+// are written. They share one namespace but live in separate unique indexes,
+// so the check and the write have to be serialized.
+const productCodesLock = 8_000_801
+
+// ensureCodesFree fails with gorm.ErrDuplicatedKey, as the unique indexes
+// would, when one of the codes or one of its barcode forms is taken by
+// another product or variant. The lock is held until the transaction ends.
+func ensureCodesFree(tx *gorm.DB, codes []string, productID, variantID uint) error {
+	var forms []string
+	for _, code := range codes {
+		if code != "" {
+			forms = append(forms, barcode.Equivalents(code)...)
+		}
+	}
+	if len(forms) == 0 {
+		return nil
+	}
 
-	err := r.db.Transaction(func(tx *gorm.DB) error {
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", productCodesLock).Error; err != nil {
+		return err
+	}
+
+	taken, err := codesTaken(tx, forms, productID, variantID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return gorm.ErrDuplicatedKey
+	}
+	return nil
+}
 
-		var product product.Product
-		if err := tx.Preload("Categories").First(&product, productID).Error; err != nil {
+// codesTaken tells whether a product other than productID or a variant other
+// than variantID holds one of the codes.
+func codesTaken(db *gorm.DB, codes []string, productID, variantID uint) (bool, error) {
+	var count int64
+	if err := db.Unscoped().Model(&product.Product{}).
+		Where("(barcode IN ? OR sku IN ?) AND id <> ?", codes, codes, productID).
+		Count(&count).Error; err != nil || count > 0 {
+		return count > 0, err
+	}
+
+	err := db.Model(&product_variant.ProductVariant{}).
+		Where("sku IN ? AND id <> ?", codes, variantID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *GormProductRepository) DeleteBySlug(ctx context.Context, productID uint) error {
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+
+		var p product.Product
+		if err := tx.Preload("Categories").First(&p, productID).Error; err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return errors.New("produto não encontrado")
+				return product.ErrProductNotFound
 			}
 			return err
 		}
 
-		if err := tx.Model(&product).Association("Categories").Clear(); err != nil {
+		// Categories, variants, images and slug aliases are kept, so that a
+		// restored product comes back as it was.
+		if err := tx.Delete(&p).Error; err != nil {
 			return err
 		}
 
-		if err := tx.Unscoped().Delete(&product).Error; err != nil {
+		return recordProductAudit(tx, p.ID, product_audit.DeletedAction, productAuditSnapshot(p), nil, "Product moved to trash")
+
+	})
+
+	return err
+}
+
+func (r *GormProductRepository) GetTrash(ctx context.Context, page int, pageSize int) ([]product.Product, int64, error) {
+	var products []product.Product
+	var total int64
+
+	query := r.db.WithContext(ctx).Unscoped().Model(&product.Product{}).Where("deleted_at IS NOT NULL")
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.Preload("Images").Preload("Variants").Preload("Categories").
+		Order("deleted_at DESC").Order("id ASC").
+		Offset((page - 1) * pageSize).Limit(pageSize).Find(&products).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return products, total, nil
+}
+
+func (r *GormProductRepository) Restore(ctx context.Context, slug string) (*product.Product, error) {
+	var p product.Product
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("slug = ? AND deleted_at IS NOT NULL", slug).First(&p).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return product.ErrProductNotInTrash
+			}
 			return err
 		}
 
-		return nil
+		if err := tx.Unscoped().Model(&p).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		p.DeletedAt = gorm.DeletedAt{}
 
+		return recordProductAudit(tx, p.ID, product_audit.RestoredAction, nil, productAuditSnapshot(p), "Product restored from trash")
 	})
+	if err != nil {
+		return nil, err
+	}
 
-	return err
+	return r.FindBySlug(slug)
+}
+
+// FindTrashedBefore lists the products moved to the trash before cutoff, with
+// their images, for the purge job.
+func (r *GormProductRepository) FindTrashedBefore(ctx context.Context, cutoff time.Time) ([]product.Product, error) {
+	var products []product.Product
+	err := r.db.WithContext(ctx).Unscoped().Preload("Images").
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Order("deleted_at ASC").Find(&products).Error
+	if err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// Purge permanently removes a trashed product and everything hanging from it.
+// Products referenced by sales are kept, the sale history needs them.
+func (r *GormProductRepository) Purge(ctx context.Context, productID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var p product.Product
+		if err := tx.Unscoped().Preload("Categories").
+			Where("id = ? AND deleted_at IS NOT NULL", productID).First(&p).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return product.ErrProductNotInTrash
+			}
+			return err
+		}
+
+		var sales int64
+		if err := tx.Table("sale_items").Where("product_id = ?", p.ID).Count(&sales).Error; err != nil {
+			return err
+		}
+		if sales > 0 {
+			return product.ErrProductHasSales
+		}
+
+		if err := tx.Model(&p).Association("Categories").Clear(); err != nil {
+			return err
+		}
+
+		if err := tx.Where("product_id = ?", p.ID).Delete(&product_variant.ProductVariant{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("product_id = ?", p.ID).Delete(&product_image.ProductImage{}).Error; err != nil {
+			return err
+		}
+
+		if err := deleteProductSlugAliases(tx, p.ID); err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().Delete(&p).Error; err != nil {
+			return err
+		}
+
+		return recordProductAudit(tx, p.ID, product_audit.PurgedAction, productAuditSnapshot(p), nil, "Product permanently deleted from trash")
+	})
 }
 
-func (r *GormProductRepository) UpdateBySlug(slug string, updatedProduct product.Product) (product.Product, error) {
+func (r *GormProductRepository) UpdateBySlug(ctx context.Context, slug string, updatedProduct product.Product) (product.Product, error) {
 	var existingProduct product.Product
 
-	err := r.db.Transaction(func(tx *gorm.DB) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Categories").Where("slug = ?", slug).First(&existingProduct).Error; err != nil {
+			return err
+		}
 
-		if err := tx.Preload("Categories").Where("slug = ?", slug).First(&existingProduct).Error; err != nil {
+		if err := ensureCodesFree(tx, productCodes(updatedProduct), existingProduct.ID, 0); err != nil {
 			return err
 		}
 
-		existingProduct.Name = updatedProduct.Name
-		existingProduct.Description = updatedProduct.Description
-		existingProduct.Price = updatedProduct.Price
-		existingProduct.Cost = updatedProduct.Cost
-		existingProduct.Stock = updatedProduct.Stock
+		oldSnapshot := productAuditSnapshot(existingProduct)
+
+		// Products with variants keep their stock as the sum of the variants.
+		hasVariants, err := productHasVariants(tx, existingProduct.ID)
+		if err != nil {
+			return err
+		}
+		if hasVariants {
+			updatedProduct.Stock = existingProduct.Stock
+		}
+
+		changed := existingProduct
+		if updatedProduct.Slug != "" {
+			changed.Slug = updatedProduct.Slug
+		}
+		changed.Name = updatedProduct.Name
+		changed.SKU = updatedProduct.SKU
+		changed.Barcode = updatedProduct.Barcode
+		changed.Description = updatedProduct.Description
+		changed.Price = updatedProduct.Price
+		changed.Cost = updatedProduct.Cost
+		changed.Stock = updatedProduct.Stock
+		changed.MinStock = updatedProduct.MinStock
+		changed.AvailableFrom = updatedProduct.AvailableFrom
+		changed.AvailableUntil = updatedProduct.AvailableUntil
+		changed.Categories = updatedProduct.Categories
+
+		// An update that changes nothing leaves the row, its updated_at and the
+		// audit log as they are.
+		oldValue, newValue := diffSnapshots(oldSnapshot, productAuditSnapshot(changed))
+		if len(newValue) == 0 {
+			return nil
+		}
+
+		if existingProduct.Stock != changed.Stock {
+			movement := stock_movement.StockMovement{
+				Type:   stock_movement.AdjustmentMovement,
+				Reason: "Estoque alterado na edição do produto",
+			}
+			delta := changed.Stock - existingProduct.Stock
+			if err := recordStockMovement(tx, movement, existingProduct.ID, delta, changed.Stock); err != nil {
+				return err
+			}
+		}
+
+		if changed.Slug != existingProduct.Slug {
+			if err := renameProductSlug(tx, existingProduct.ID, existingProduct.Slug, changed.Slug); err != nil {
+				return err
+			}
+		}
+
+		existingProduct = changed
 		existingProduct.UpdatedAt = time.Now()
 
 		if err := tx.Model(&existingProduct).Association("Categories").Clear(); err != nil {
@@ -118,32 +646,285 @@ func (r *GormProductRepository) UpdateBySlug(slug string, updatedProduct product
 			}
 		}
 
-		if err := tx.Save(&existingProduct).Error; err != nil {
+		if err := tx.Omit("Categories").Save(&existingProduct).Error; err != nil {
 			return err
 		}
 
-		return nil
+		if err := refreshProductSearchVector(tx, existingProduct.ID); err != nil {
+			return err
+		}
+
+		return recordProductAudit(tx, existingProduct.ID, product_audit.UpdatedAction, oldValue, newValue, "Product updated")
 	})
 
 	if err != nil {
-		return existingProduct, err
+		return existingProduct, r.translateProductError(err, existingProduct.ID, updatedProduct.Slug)
 	}
 
 	return existingProduct, nil
 }
 
-func (r *GormProductRepository) ClearProductCategories(productID uint) error {
-	return r.db.Model(&product.Product{ID: productID}).Association("Categories").Clear()
+func (r *GormProductRepository) UpdateProductCategories(ctx context.Context, p *product.Product) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+
+		var current product.Product
+		if err := tx.Preload("Categories").First(&current, p.ID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(p).Association("Categories").Replace(p.Categories); err != nil {
+			return err
+		}
+
+		if err := refreshProductSearchVector(tx, p.ID); err != nil {
+			return err
+		}
+
+		oldValue, newValue := diffSnapshots(
+			map[string]interface{}{"category_ids": productAuditSnapshot(current)["category_ids"]},
+			map[string]interface{}{"category_ids": productAuditSnapshot(*p)["category_ids"]})
+		if len(newValue) == 0 {
+			return nil
+		}
+		return recordProductAudit(tx, p.ID, product_audit.CategoriesLinkedAction, oldValue, newValue, "Product categories linked")
+	})
 }
 
-func (r *GormProductRepository) UpdateProductCategories(product *product.Product) error {
-	return r.db.Model(product).Association("Categories").Replace(product.Categories)
+func (r *GormProductRepository) SwitchAvailable(ctx context.Context, p product.Product) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+
+		oldAvailable := p.Available
+		updates := map[string]interface{}{"available": !oldAvailable}
+
+		// Switching a product back on after its window ended drops the expired
+		// end date, otherwise the scheduler would switch it off again.
+		if !oldAvailable && p.AvailableUntil != nil && !p.AvailableUntil.After(time.Now()) {
+			updates["available_until"] = nil
+		}
+
+		if err := tx.Model(&p).Where("slug = ?", p.Slug).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		oldValue := map[string]interface{}{"available": oldAvailable}
+		newValue := map[string]interface{}{"available": !oldAvailable}
+		return recordProductAudit(tx, p.ID, product_audit.AvailabilitySwitchAction, oldValue, newValue, "Product availability switched")
+	})
 }
 
-func (r *GormProductRepository) SwitchAvailable(product product.Product) error {
-	return r.db.Model(&product).Where("slug = ?", product.Slug).Update("available", !product.Available).Error
+// ApplyAvailabilitySchedule applies the availability windows that started or
+// ended by now. A reached available_from is cleared once applied, so a
+// product switched off by hand afterwards stays off.
+func (r *GormProductRepository) ApplyAvailabilitySchedule(ctx context.Context, now time.Time) (int, error) {
+	changed := 0
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var products []product.Product
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Preload("Categories").
+			Where("available_from <= ? OR (available_until <= ? AND available = ?)", now, now, true).
+			Find(&products).Error; err != nil {
+			return err
+		}
+
+		for _, p := range products {
+			oldSnapshot := productAuditSnapshot(p)
+
+			description := "Produto disponibilizado pelo agendamento"
+			if p.AvailableUntil != nil && !p.AvailableUntil.After(now) {
+				p.Available = false
+				description = "Produto indisponibilizado pelo agendamento"
+			} else {
+				p.Available = true
+			}
+			p.AvailableFrom = nil
+
+			if err := tx.Model(&p).Updates(map[string]interface{}{
+				"available":      p.Available,
+				"available_from": nil,
+			}).Error; err != nil {
+				return err
+			}
+
+			oldValue, newValue := diffSnapshots(oldSnapshot, productAuditSnapshot(p))
+			if len(newValue) == 0 {
+				continue
+			}
+			if err := recordProductAudit(tx, p.ID, product_audit.AvailabilitySwitchAction, oldValue, newValue, description); err != nil {
+				return err
+			}
+			changed++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return changed, nil
+}
+
+func (r *GormProductRepository) GetLowStock(ctx context.Context, page int, pageSize int) ([]product.Product, int64, error) {
+	var products []product.Product
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&product.Product{}).Where("min_stock > 0 AND stock <= min_stock")
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.Preload("Images").Preload("Variants").Preload("Categories").
+		Order("stock - min_stock ASC").Order("id ASC").
+		Offset((page - 1) * pageSize).Limit(pageSize).Find(&products).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return products, total, nil
+}
+
+func (r *GormProductRepository) IncreaseStock(
+	ctx context.Context,
+	slug string,
+	quantity int,
+	movement stock_movement.StockMovement) (*product.Product, error) {
+	var p product.Product
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+
+		result := tx.Model(&p).Clauses(clause.Returning{}).
+			Where("slug = ? AND NOT EXISTS (?)", slug, variantsOfProduct(tx)).
+			Update("stock", gorm.Expr("stock + ?", quantity))
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return stockUpdateFailure(tx, slug)
+		}
+
+		return recordStockMovement(tx, movement, p.ID, quantity, p.Stock)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
 }
 
-func (r *GormProductRepository) UpdateProductStock(product *product.Product) error {
-	return r.db.Model(product).Update("stock", product.Stock).Error
+// DecreaseStock only touches the row when it still holds enough stock, so
+// concurrent stock-outs can never take the balance below zero.
+func (r *GormProductRepository) DecreaseStock(
+	ctx context.Context,
+	slug string,
+	quantity int,
+	movement stock_movement.StockMovement) (*product.Product, error) {
+	var p product.Product
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+
+		result := tx.Model(&p).Clauses(clause.Returning{}).
+			Where("slug = ? AND stock >= ? AND NOT EXISTS (?)", slug, quantity, variantsOfProduct(tx)).
+			Update("stock", gorm.Expr("stock - ?", quantity))
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			if err := stockUpdateFailure(tx, slug); err != nil {
+				return err
+			}
+			return product.ErrInsufficientStock
+		}
+
+		return recordStockMovement(tx, movement, p.ID, -quantity, p.Stock)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+func productHasVariants(tx *gorm.DB, productID uint) (bool, error) {
+	var count int64
+	err := tx.Model(&product_variant.ProductVariant{}).Where("product_id = ?", productID).Count(&count).Error
+	return count > 0, err
+}
+
+func variantsOfProduct(tx *gorm.DB) *gorm.DB {
+	return tx.Session(&gorm.Session{NewDB: true}).
+		Model(&product_variant.ProductVariant{}).
+		Select("1").
+		Where("product_variants.product_id = products.id")
+}
+
+// stockUpdateFailure explains why a product level stock update touched no
+// rows. It returns nil when the product exists and has no variants.
+func stockUpdateFailure(tx *gorm.DB, slug string) error {
+	var p product.Product
+	if err := tx.Select("id").Where("slug = ?", slug).First(&p).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return product.ErrProductNotFound
+		}
+		return err
+	}
+
+	hasVariants, err := productHasVariants(tx, p.ID)
+	if err != nil {
+		return err
+	}
+	if hasVariants {
+		return product.ErrProductHasVariants
+	}
+
+	return nil
+}
+
+// renameProductSlug keeps oldSlug as a redirect alias. An alias equal to the
+// new slug is dropped, since the product is published under it again.
+func renameProductSlug(tx *gorm.DB, productID uint, oldSlug string, newSlug string) error {
+	if err := tx.Where("product_id = ? AND slug = ?", productID, newSlug).Delete(&product.ProductSlugAlias{}).Error; err != nil {
+		return err
+	}
+
+	alias := product.ProductSlugAlias{
+		ProductID: productID,
+		Slug:      oldSlug,
+	}
+	return tx.Create(&alias).Error
+}
+
+func deleteProductSlugAliases(tx *gorm.DB, productID uint) error {
+	return tx.Where("product_id = ?", productID).Delete(&product.ProductSlugAlias{}).Error
+}
+
+// translateProductError maps unique violations to the domain errors. The
+// translated driver error does not name the constraint, so once the
+// transaction is rolled back the slugs are looked up to tell a slug taken by
+// another product apart from a taken code.
+func (r *GormProductRepository) translateProductError(err error, productID uint, slugs ...string) error {
+	if !errors.Is(err, gorm.ErrDuplicatedKey) {
+		return err
+	}
+
+	for _, slug := range slugs {
+		if slug == "" {
+			continue
+		}
+		var count int64
+		if lookupErr := r.db.Unscoped().Model(&product.Product{}).
+			Where("slug = ? AND id <> ?", slug, productID).
+			Count(&count).Error; lookupErr != nil {
+			return lookupErr
+		}
+		if count > 0 {
+			return product.ErrDuplicateSlug
+		}
+	}
+
+	return product.ErrDuplicateCode
 }
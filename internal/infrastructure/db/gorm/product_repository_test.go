@@ -0,0 +1,278 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/reinaldo-silva/savina-stock/internal/domain/product"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/product_audit"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/product_variant"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/sale"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/sale_item"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/stock_movement"
+	"github.com/segmentio/ksuid"
+	"gorm.io/gorm"
+)
+
+// newTestDB connects to the PostgreSQL database in TEST_DATABASE_URL. The
+// repository relies on PostgreSQL features, so these tests are skipped
+// without one.
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	return NewGormDB(dsn)
+}
+
+// createTestProduct saves a product under a random slug and removes it, with
+// its variants, movements and audit entries, when the test ends.
+func createTestProduct(t *testing.T, db *gorm.DB, stock int) *product.Product {
+	t.Helper()
+	slug := "test-" + strings.ToLower(ksuid.New().String())
+	p := &product.Product{Name: "Test product", Slug: slug, Price: 10, Stock: stock, Available: true}
+	if err := db.Create(p).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		var saleIDs []uint
+		db.Model(&sale_item.SaleItem{}).Where("product_id = ?", p.ID).Pluck("sale_id", &saleIDs)
+		db.Where("product_id = ?", p.ID).Delete(&sale_item.SaleItem{})
+		if len(saleIDs) > 0 {
+			db.Delete(&sale.Sale{}, saleIDs)
+		}
+		db.Where("product_id = ?", p.ID).Delete(&stock_movement.StockMovement{})
+		db.Where("product_id = ?", p.ID).Delete(&product_variant.ProductVariant{})
+		db.Where("product_id = ?", p.ID).Delete(&product_audit.ProductAudit{})
+		db.Unscoped().Delete(&product.Product{}, p.ID)
+	})
+	return p
+}
+
+func TestDecreaseStockNeverGoesNegative(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewGormProductRepository(db)
+	p := createTestProduct(t, db, 10)
+
+	const stockOuts = 50
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded, insufficient := 0, 0
+
+	for i := 0; i < stockOuts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			updated, err := repo.DecreaseStock(context.Background(), p.Slug, 1, stock_movement.StockMovement{Type: stock_movement.OutMovement})
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				succeeded++
+				if updated.Stock < 0 {
+					t.Errorf("stock went down to %d", updated.Stock)
+				}
+			case errors.Is(err, product.ErrInsufficientStock):
+				insufficient++
+			default:
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != 10 || insufficient != stockOuts-10 {
+		t.Fatalf("got %d stock-outs and %d refusals, want 10 and %d", succeeded, insufficient, stockOuts-10)
+	}
+
+	var stored product.Product
+	if err := db.First(&stored, p.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Stock != 0 {
+		t.Fatalf("stock is %d, want 0", stored.Stock)
+	}
+
+	var movements int64
+	if err := db.Model(&stock_movement.StockMovement{}).Where("product_id = ?", p.ID).Count(&movements).Error; err != nil {
+		t.Fatal(err)
+	}
+	if movements != 10 {
+		t.Fatalf("%d movements were recorded, want 10", movements)
+	}
+}
+
+func TestDecreaseVariantStockNeverGoesNegative(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewGormVariantRepository(db)
+	p := createTestProduct(t, db, 5)
+	variant := &product_variant.ProductVariant{ProductID: p.ID, SKU: p.Slug, Stock: 5, Available: true}
+	if err := db.Create(variant).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	const stockOuts = 30
+	var wg sync.WaitGroup
+	for i := 0; i < stockOuts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := repo.DecreaseStock(context.Background(), variant.ID, 1, stock_movement.StockMovement{Type: stock_movement.OutMovement})
+			if err != nil && !errors.Is(err, product_variant.ErrInsufficientStock) {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var stored product_variant.ProductVariant
+	if err := db.First(&stored, variant.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	var storedProduct product.Product
+	if err := db.First(&storedProduct, p.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Stock != 0 || storedProduct.Stock != 0 {
+		t.Fatalf("variant stock is %d and product stock is %d, want both 0", stored.Stock, storedProduct.Stock)
+	}
+}
+
+func TestCreateTellsTakenSlugFromTakenCode(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewGormProductRepository(db)
+	existing := createTestProduct(t, db, 0)
+	sku := existing.Slug
+	if err := db.Model(existing).Update("sku", sku).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	sameSlug := &product.Product{Name: "Test product", Slug: existing.Slug, Price: 10}
+	if err := repo.Create(context.Background(), sameSlug); !errors.Is(err, product.ErrDuplicateSlug) {
+		t.Fatalf("got %v, want ErrDuplicateSlug", err)
+	}
+
+	sameCode := &product.Product{Name: "Test product", Slug: existing.Slug + "-2", SKU: &sku, Price: 10}
+	if err := repo.Create(context.Background(), sameCode); !errors.Is(err, product.ErrDuplicateCode) {
+		t.Fatalf("got %v, want ErrDuplicateCode", err)
+	}
+}
+
+func TestSoldVariantCannotBeDeletedOrSoldWhenUnavailable(t *testing.T) {
+	db := newTestDB(t)
+	variantRepo := NewGormVariantRepository(db)
+	saleRepo := NewGormSaleRepository(db)
+	p := createTestProduct(t, db, 0)
+	variant := &product_variant.ProductVariant{ProductID: p.ID, SKU: p.Slug, Stock: 5, Available: true}
+	if err := db.Create(variant).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&product.Product{}).Where("id = ?", p.ID).Update("stock", 5).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	sold := &sale.Sale{SaleProducts: []sale_item.SaleItem{{ProductID: p.ID, VariantID: &variant.ID, Quantity: 1}}}
+	if err := saleRepo.Create(context.Background(), sold); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := variantRepo.Delete(context.Background(), variant); !errors.Is(err, product_variant.ErrVariantHasSales) {
+		t.Fatalf("got %v, want ErrVariantHasSales", err)
+	}
+
+	if err := db.Model(variant).Update("available", false).Error; err != nil {
+		t.Fatal(err)
+	}
+	unavailable := &sale.Sale{SaleProducts: []sale_item.SaleItem{{ProductID: p.ID, VariantID: &variant.ID, Quantity: 1}}}
+	if err := saleRepo.Create(context.Background(), unavailable); !errors.Is(err, product_variant.ErrVariantUnavailable) {
+		t.Fatalf("got %v, want ErrVariantUnavailable", err)
+	}
+}
+
+func TestProductsAndVariantsShareOneCodeNamespace(t *testing.T) {
+	db := newTestDB(t)
+	productRepo := NewGormProductRepository(db)
+	variantRepo := NewGormVariantRepository(db)
+	p := createTestProduct(t, db, 0)
+	code := strings.ToUpper(p.Slug)
+	ean := "0036000291452"
+	if err := db.Model(p).Updates(map[string]interface{}{"sku": code, "barcode": ean}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	variant := &product_variant.ProductVariant{SKU: code}
+	if err := variantRepo.Create(context.Background(), p.Slug, variant); !errors.Is(err, product_variant.ErrDuplicateSKU) {
+		t.Fatalf("a variant took the product SKU: got %v, want ErrDuplicateSKU", err)
+	}
+
+	variant = &product_variant.ProductVariant{SKU: code + "-M"}
+	if err := variantRepo.Create(context.Background(), p.Slug, variant); err != nil {
+		t.Fatal(err)
+	}
+	other := &product.Product{Name: "Test product", Slug: p.Slug + "-2", SKU: &variant.SKU, Price: 10}
+	if err := productRepo.Create(context.Background(), other); !errors.Is(err, product.ErrDuplicateCode) {
+		t.Fatalf("a product took a variant SKU: got %v, want ErrDuplicateCode", err)
+	}
+
+	upc := ean[1:]
+	other = &product.Product{Name: "Test product", Slug: p.Slug + "-3", SKU: &upc, Price: 10}
+	if err := productRepo.Create(context.Background(), other); !errors.Is(err, product.ErrDuplicateCode) {
+		t.Fatalf("the UPC-A form of a barcode was accepted: got %v, want ErrDuplicateCode", err)
+	}
+
+	found, err := productRepo.FindByCode([]string{code + "-M"})
+	if err != nil || found.ID != p.ID {
+		t.Fatalf("got %v, %v, want the product of the variant", found, err)
+	}
+}
+
+func TestUpdateWithoutChangesRecordsNoAudit(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewGormProductRepository(db)
+	p := createTestProduct(t, db, 3)
+
+	countAudits := func() int64 {
+		t.Helper()
+		var count int64
+		if err := db.Model(&product_audit.ProductAudit{}).Where("product_id = ?", p.ID).Count(&count).Error; err != nil {
+			t.Fatal(err)
+		}
+		return count
+	}
+
+	var before product.Product
+	if err := db.First(&before, p.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.UpdateBySlug(context.Background(), p.Slug, *p); err != nil {
+		t.Fatal(err)
+	}
+	if count := countAudits(); count != 0 {
+		t.Fatalf("an update without changes recorded %d audit entries", count)
+	}
+
+	var after product.Product
+	if err := db.First(&after, p.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !after.UpdatedAt.Equal(before.UpdatedAt) {
+		t.Fatalf("updated_at moved from %v to %v without changes", before.UpdatedAt, after.UpdatedAt)
+	}
+
+	changed := *p
+	changed.Name = "Renamed product"
+	if _, err := repo.UpdateBySlug(context.Background(), p.Slug, changed); err != nil {
+		t.Fatal(err)
+	}
+	if count := countAudits(); count != 1 {
+		t.Fatalf("a rename recorded %d audit entries, want 1", count)
+	}
+}
@@ -0,0 +1,138 @@
+package gorm
+
+import (
+	"context"
+
+	"github.com/reinaldo-silva/savina-stock/internal/domain/product"
+	"gorm.io/gorm"
+)
+
+// The search vector joins the category names, which live in other tables, so
+// it is kept in a plain column refreshed by the repositories instead of a
+// generated column. Weights: name and SKU (A), categories (B), description (C).
+const searchVectorExpression = `
+	setweight(to_tsvector('portuguese', immutable_unaccent(coalesce(products.name, ''))), 'A') ||
+	setweight(to_tsvector('portuguese', immutable_unaccent(coalesce(products.sku, ''))), 'A') ||
+	setweight(to_tsvector('portuguese', immutable_unaccent(coalesce((
+		SELECT string_agg(c.name, ' ')
+		FROM categories c
+		JOIN product_categories pc ON pc.category_id = c.id
+		WHERE pc.product_id = products.id), ''))), 'B') ||
+	setweight(to_tsvector('portuguese', immutable_unaccent(coalesce(products.description, ''))), 'C')`
+
+const searchQueryExpression = "websearch_to_tsquery('portuguese', immutable_unaccent(?))"
+
+const searchHeadlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxWords=25, MinWords=8, MaxFragments=2"
+
+// searchSnippetText is the text highlighted by ts_headline, HTML escaped
+// beforehand so the only markup in a snippet is the <mark> tags. The parser
+// reads the entities as single tokens and leaves them untouched.
+const searchSnippetText = `replace(replace(replace(replace(
+	products.name || ' ' || coalesce(products.description, ''),
+	'&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;')`
+
+// migrateProductSearch installs what full-text search needs on top of the
+// tables created by AutoMigrate. unaccent is not immutable, so it is wrapped
+// to be usable in the index expressions.
+func migrateProductSearch(db *gorm.DB) error {
+	statements := []string{
+		`CREATE EXTENSION IF NOT EXISTS unaccent`,
+		`CREATE OR REPLACE FUNCTION immutable_unaccent(text) RETURNS text
+			AS $$ SELECT public.unaccent('public.unaccent', $1) $$
+			LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT`,
+		`ALTER TABLE products ADD COLUMN IF NOT EXISTS search_vector tsvector`,
+		`CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING GIN (search_vector)`,
+	}
+
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+
+	return db.Exec("UPDATE products SET search_vector = " + searchVectorExpression + " WHERE search_vector IS NULL").Error
+}
+
+func refreshProductSearchVector(tx *gorm.DB, productID uint) error {
+	return tx.Exec("UPDATE products SET search_vector = "+searchVectorExpression+" WHERE products.id = ?", productID).Error
+}
+
+func refreshCategorySearchVectors(tx *gorm.DB, categoryID uint) error {
+	return tx.Exec("UPDATE products SET search_vector = "+searchVectorExpression+
+		" WHERE products.id IN (SELECT product_id FROM product_categories WHERE category_id = ?)", categoryID).Error
+}
+
+type searchHit struct {
+	ID      uint
+	Rank    float64
+	Snippet string
+}
+
+func (r *GormProductRepository) Search(
+	ctx context.Context,
+	query string,
+	page int,
+	pageSize int,
+	filter product.ProductFilter) ([]product.SearchResult, int64, error) {
+	var total int64
+
+	base := r.applyProductFilter(r.db.WithContext(ctx).Model(&product.Product{}), filter).
+		Where("search_vector @@ "+searchQueryExpression, query)
+
+	if err := base.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	hitsQuery := base.Select(
+		"products.id, ts_rank_cd(search_vector, "+searchQueryExpression+") AS rank, "+
+			"ts_headline('portuguese', "+searchSnippetText+", "+searchQueryExpression+", ?) AS snippet",
+		query, query, searchHeadlineOptions)
+
+	// Results are ordered by relevance unless an explicit sort was requested.
+	if filter.SortBy == "" {
+		hitsQuery = hitsQuery.Order("rank DESC").Order("products.id ASC")
+	} else {
+		hitsQuery = orderProducts(hitsQuery, filter)
+	}
+
+	var hits []searchHit
+	err := hitsQuery.Offset((page - 1) * pageSize).Limit(pageSize).Scan(&hits).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if len(hits) == 0 {
+		return []product.SearchResult{}, total, nil
+	}
+
+	ids := make([]uint, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+
+	var products []product.Product
+	if err := r.db.WithContext(ctx).Preload("Images").Preload("Variants").Preload("Categories").
+		Where("id IN ?", ids).Find(&products).Error; err != nil {
+		return nil, 0, err
+	}
+
+	productsByID := make(map[uint]product.Product, len(products))
+	for _, p := range products {
+		productsByID[p.ID] = p
+	}
+
+	results := make([]product.SearchResult, 0, len(hits))
+	for _, hit := range hits {
+		p, ok := productsByID[hit.ID]
+		if !ok {
+			continue
+		}
+		results = append(results, product.SearchResult{
+			Product: p,
+			Rank:    hit.Rank,
+			Snippet: hit.Snippet,
+		})
+	}
+
+	return results, total, nil
+}
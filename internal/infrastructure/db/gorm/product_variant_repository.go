@@ -0,0 +1,304 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+
+	"github.com/reinaldo-silva/savina-stock/internal/domain/product"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/product_audit"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/product_variant"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/stock_movement"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GormVariantRepository struct {
+	db *gorm.DB
+}
+
+func NewGormVariantRepository(db *gorm.DB) product_variant.VariantRepository {
+	return &GormVariantRepository{db: db}
+}
+
+func (r *GormVariantRepository) FindByProductSlug(slug string) ([]product_variant.ProductVariant, error) {
+	var variants []product_variant.ProductVariant
+	if err := r.db.Joins("JOIN products ON products.id = product_variants.product_id AND products.deleted_at IS NULL").
+		Where("products.slug = ?", slug).
+		Order("product_variants.id ASC").
+		Find(&variants).Error; err != nil {
+		return nil, err
+	}
+	return variants, nil
+}
+
+func (r *GormVariantRepository) FindByIDAndProductSlug(id uint, slug string) (*product_variant.ProductVariant, error) {
+	var variant product_variant.ProductVariant
+	if err := r.db.Joins("JOIN products ON products.id = product_variants.product_id AND products.deleted_at IS NULL").
+		Where("product_variants.id = ? AND products.slug = ?", id, slug).
+		First(&variant).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, product_variant.ErrVariantNotFound
+		}
+		return nil, err
+	}
+	return &variant, nil
+}
+
+// Create attaches a variant to the product. A product's stock becomes the sum
+// of its variants, so the first variant is only accepted while the product
+// holds no stock of its own.
+func (r *GormVariantRepository) Create(ctx context.Context, slug string, variant *product_variant.ProductVariant) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+
+		var p product.Product
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("slug = ?", slug).First(&p).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return product_variant.ErrProductNotFound
+			}
+			return err
+		}
+
+		var variantCount int64
+		if err := tx.Model(&product_variant.ProductVariant{}).Where("product_id = ?", p.ID).Count(&variantCount).Error; err != nil {
+			return err
+		}
+
+		if variantCount == 0 && p.Stock != 0 {
+			return product_variant.ErrProductHasBaseStock
+		}
+
+		if err := ensureCodesFree(tx, []string{variant.SKU}, 0, 0); err != nil {
+			return err
+		}
+
+		variant.ProductID = p.ID
+		if err := tx.Create(variant).Error; err != nil {
+			return err
+		}
+
+		if variant.Stock != 0 {
+			if err := adjustProductStock(tx, p.ID, variant.Stock); err != nil {
+				return err
+			}
+
+			movement := stock_movement.StockMovement{
+				VariantID: &variant.ID,
+				Type:      stock_movement.AdjustmentMovement,
+				Reason:    "Estoque inicial da variação",
+			}
+			if err := recordStockMovement(tx, movement, p.ID, variant.Stock, variant.Stock); err != nil {
+				return err
+			}
+		}
+
+		return recordProductAudit(tx, p.ID, product_audit.VariantCreatedAction, nil, variantAuditSnapshot(*variant), "Product variant created")
+	})
+
+	return translateVariantError(err)
+}
+
+func (r *GormVariantRepository) Update(ctx context.Context, variant *product_variant.ProductVariant) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+
+		var current product_variant.ProductVariant
+		if err := tx.First(&current, variant.ID).Error; err != nil {
+			return err
+		}
+
+		if err := ensureCodesFree(tx, []string{variant.SKU}, 0, variant.ID); err != nil {
+			return err
+		}
+
+		if err := tx.Model(variant).Select("sku", "attributes", "price").Updates(variant).Error; err != nil {
+			return err
+		}
+
+		oldValue, newValue := diffSnapshots(variantAuditSnapshot(current), variantAuditSnapshot(*variant))
+		if len(newValue) == 0 {
+			return nil
+		}
+		oldValue["variant_id"] = variant.ID
+		newValue["variant_id"] = variant.ID
+		return recordProductAudit(tx, variant.ProductID, product_audit.VariantUpdatedAction, oldValue, newValue, "Product variant updated")
+	})
+
+	return translateVariantError(err)
+}
+
+func (r *GormVariantRepository) Delete(ctx context.Context, variant *product_variant.ProductVariant) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+
+		var current product_variant.ProductVariant
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, variant.ID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return product_variant.ErrVariantNotFound
+			}
+			return err
+		}
+
+		if current.Stock != 0 {
+			if err := adjustProductStock(tx, current.ProductID, -current.Stock); err != nil {
+				return err
+			}
+
+			movement := stock_movement.StockMovement{
+				VariantID: &current.ID,
+				Type:      stock_movement.AdjustmentMovement,
+				Reason:    "Variação removida",
+			}
+			if err := recordStockMovement(tx, movement, current.ProductID, -current.Stock, 0); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Delete(&current).Error; err != nil {
+			if errors.Is(err, gorm.ErrForeignKeyViolated) {
+				return product_variant.ErrVariantHasSales
+			}
+			return err
+		}
+
+		return recordProductAudit(tx, current.ProductID, product_audit.VariantDeletedAction, variantAuditSnapshot(current), nil, "Product variant deleted")
+	})
+}
+
+func (r *GormVariantRepository) SwitchAvailable(ctx context.Context, variant product_variant.ProductVariant) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+
+		oldAvailable := variant.Available
+
+		if err := tx.Model(&variant).Update("available", !oldAvailable).Error; err != nil {
+			return err
+		}
+
+		oldValue := map[string]interface{}{"variant_id": variant.ID, "available": oldAvailable}
+		newValue := map[string]interface{}{"variant_id": variant.ID, "available": !oldAvailable}
+		return recordProductAudit(tx, variant.ProductID, product_audit.VariantAvailabilityAction, oldValue, newValue, "Product variant availability switched")
+	})
+}
+
+func (r *GormVariantRepository) IncreaseStock(
+	ctx context.Context,
+	variantID uint,
+	quantity int,
+	movement stock_movement.StockMovement) (*product_variant.ProductVariant, error) {
+	var variant product_variant.ProductVariant
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+
+		result := tx.Model(&variant).Clauses(clause.Returning{}).
+			Where("id = ?", variantID).
+			Update("stock", gorm.Expr("stock + ?", quantity))
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return product_variant.ErrVariantNotFound
+		}
+
+		if err := adjustProductStock(tx, variant.ProductID, quantity); err != nil {
+			return err
+		}
+
+		movement.VariantID = &variant.ID
+		return recordStockMovement(tx, movement, variant.ProductID, quantity, variant.Stock)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &variant, nil
+}
+
+func (r *GormVariantRepository) DecreaseStock(
+	ctx context.Context,
+	variantID uint,
+	quantity int,
+	movement stock_movement.StockMovement) (*product_variant.ProductVariant, *product_variant.ProductStock, error) {
+	var variant product_variant.ProductVariant
+	var productStock product_variant.ProductStock
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+
+		if err := decreaseVariantStock(tx, &variant, variantID, quantity); err != nil {
+			return err
+		}
+
+		// Read after the update, while the row is still locked by it, so the
+		// stock is the one this stock-out left.
+		if err := tx.Unscoped().Model(&product.Product{}).
+			Select("id", "slug", "name", "stock", "min_stock").
+			Where("id = ?", variant.ProductID).
+			Scan(&productStock).Error; err != nil {
+			return err
+		}
+
+		movement.VariantID = &variant.ID
+		return recordStockMovement(tx, movement, variant.ProductID, -quantity, variant.Stock)
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &variant, &productStock, nil
+}
+
+// decreaseVariantStock removes quantity from the variant and from its product's
+// aggregated stock, refusing to take the variant below zero.
+func decreaseVariantStock(tx *gorm.DB, variant *product_variant.ProductVariant, variantID uint, quantity int) error {
+	result := tx.Model(variant).Clauses(clause.Returning{}).
+		Where("id = ? AND stock >= ?", variantID, quantity).
+		Update("stock", gorm.Expr("stock - ?", quantity))
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		var count int64
+		if err := tx.Model(&product_variant.ProductVariant{}).Where("id = ?", variantID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return product_variant.ErrVariantNotFound
+		}
+		return product_variant.ErrInsufficientStock
+	}
+
+	return adjustProductStock(tx, variant.ProductID, -quantity)
+}
+
+// adjustProductStock keeps the product stock equal to the sum of its variants.
+// It is unscoped so a product in the trash stays consistent too, and it fails
+// rather than letting the variant stock change alone.
+func adjustProductStock(tx *gorm.DB, productID uint, delta int) error {
+	result := tx.Unscoped().Model(&product.Product{}).Where("id = ?", productID).
+		Update("stock", gorm.Expr("stock + ?", delta))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return product_variant.ErrProductNotFound
+	}
+	return nil
+}
+
+func variantAuditSnapshot(v product_variant.ProductVariant) map[string]interface{} {
+	return map[string]interface{}{
+		"variant_id": v.ID,
+		"sku":        v.SKU,
+		"attributes": v.Attributes,
+		"price":      v.Price,
+		"stock":      v.Stock,
+		"available":  v.Available,
+	}
+}
+
+func translateVariantError(err error) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return product_variant.ErrDuplicateSKU
+	}
+	return err
+}
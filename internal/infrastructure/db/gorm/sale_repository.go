@@ -0,0 +1,246 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/reinaldo-silva/savina-stock/internal/domain/product"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/product_variant"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/sale"
+	"github.com/reinaldo-silva/savina-stock/internal/domain/stock_movement"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GormSaleRepository struct {
+	db *gorm.DB
+}
+
+func NewGormSaleRepository(db *gorm.DB) sale.SaleRepository {
+	return &GormSaleRepository{db: db}
+}
+
+func (r *GormSaleRepository) GetAll(
+	ctx context.Context,
+	page int,
+	pageSize int,
+	startDate *time.Time,
+	endDate *time.Time) ([]sale.Sale, int64, error) {
+	var sales []sale.Sale
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&sale.Sale{})
+
+	if startDate != nil {
+		query = query.Where("created_at >= ?", *startDate)
+	}
+
+	if endDate != nil {
+		query = query.Where("created_at < ?", endDate.AddDate(0, 0, 1))
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.Preload("SaleProducts").Order("created_at DESC").Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&sales).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return sales, total, nil
+}
+
+func (r *GormSaleRepository) FindByID(id uint) (*sale.Sale, error) {
+	var s sale.Sale
+	// Products in the trash still show in the sales they were part of.
+	if err := r.db.Preload("SaleProducts.Product", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).Preload("SaleProducts.Variant").First(&s, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, sale.ErrSaleNotFound
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *GormSaleRepository) Create(ctx context.Context, s *sale.Sale) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+
+		var total float64
+		balances := make([]int, len(s.SaleProducts))
+		for i := range s.SaleProducts {
+			item := &s.SaleProducts[i]
+
+			var p product.Product
+			if err := tx.First(&p, item.ProductID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return fmt.Errorf("%w: product with ID %d does not exist", sale.ErrInvalidSale, item.ProductID)
+				}
+				return err
+			}
+
+			unitPrice := p.Price
+			if item.VariantID != nil {
+				var variant product_variant.ProductVariant
+				if err := tx.Where("id = ? AND product_id = ?", *item.VariantID, p.ID).First(&variant).Error; err != nil {
+					if errors.Is(err, gorm.ErrRecordNotFound) {
+						return fmt.Errorf("%w: variant with ID %d does not exist for product %s", sale.ErrInvalidSale, *item.VariantID, p.Slug)
+					}
+					return err
+				}
+				if !variant.Available {
+					return fmt.Errorf("%w: %s", product_variant.ErrVariantUnavailable, variant.SKU)
+				}
+
+				if err := decreaseVariantStock(tx, &variant, variant.ID, item.Quantity); err != nil {
+					if errors.Is(err, product_variant.ErrInsufficientStock) {
+						return fmt.Errorf("%w for variant %s: requested %d", product.ErrInsufficientStock, variant.SKU, item.Quantity)
+					}
+					return err
+				}
+				balances[i] = variant.Stock
+				unitPrice = variant.EffectivePrice(p.Price)
+			} else {
+				hasVariants, err := productHasVariants(tx, p.ID)
+				if err != nil {
+					return err
+				}
+				if hasVariants {
+					return fmt.Errorf("variant_id is required for product %s: %w", p.Slug, product.ErrProductHasVariants)
+				}
+
+				var updated product.Product
+				result := tx.Model(&updated).Clauses(clause.Returning{Columns: []clause.Column{{Name: "stock"}}}).
+					Where("id = ? AND stock >= ?", p.ID, item.Quantity).
+					Update("stock", gorm.Expr("stock - ?", item.Quantity))
+				if result.Error != nil {
+					return result.Error
+				}
+				if result.RowsAffected == 0 {
+					return fmt.Errorf("%w for product %s: requested %d, available %d", product.ErrInsufficientStock, p.Slug, item.Quantity, p.Stock)
+				}
+				balances[i] = updated.Stock
+			}
+
+			item.ID = 0
+			item.UnitPrice = unitPrice
+			item.SubTotal = roundMoney(unitPrice * float64(item.Quantity))
+			total += item.SubTotal
+		}
+
+		total = roundMoney(total)
+		if s.Discount > total {
+			return sale.ErrDiscountExceedsTotal
+		}
+		s.TotalAmount = roundMoney(total - s.Discount)
+
+		if err := tx.Omit("SaleProducts").Create(s).Error; err != nil {
+			return err
+		}
+
+		movement := stock_movement.StockMovement{
+			Type:      stock_movement.SaleMovement,
+			Reason:    "Venda registrada",
+			Reference: saleReference(s.ID),
+		}
+
+		for i := range s.SaleProducts {
+			item := &s.SaleProducts[i]
+			item.SaleID = s.ID
+			if err := tx.Omit("Product", "Variant").Create(item).Error; err != nil {
+				return err
+			}
+			movement.VariantID = item.VariantID
+			if err := recordStockMovement(tx, movement, item.ProductID, -item.Quantity, balances[i]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *GormSaleRepository) Cancel(ctx context.Context, id uint) (*sale.Sale, error) {
+	var s sale.Sale
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("SaleProducts").First(&s, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return sale.ErrSaleNotFound
+			}
+			return err
+		}
+
+		if s.Status == sale.CanceledStatus {
+			return sale.ErrSaleAlreadyCanceled
+		}
+
+		movement := stock_movement.StockMovement{
+			Type:      stock_movement.ReturnMovement,
+			Reason:    "Venda cancelada",
+			Reference: saleReference(s.ID),
+		}
+
+		for _, item := range s.SaleProducts {
+			var variant product_variant.ProductVariant
+			if item.VariantID != nil {
+				result := tx.Model(&variant).Clauses(clause.Returning{Columns: []clause.Column{{Name: "stock"}}}).
+					Where("id = ?", *item.VariantID).
+					Update("stock", gorm.Expr("stock + ?", item.Quantity))
+				if result.Error != nil {
+					return result.Error
+				}
+				// The stock of a variant removed after the sale is not returned, since
+				// the product stock must stay the sum of its remaining variants.
+				if result.RowsAffected == 0 {
+					continue
+				}
+			}
+
+			var updated product.Product
+			if err := tx.Unscoped().Model(&updated).Clauses(clause.Returning{Columns: []clause.Column{{Name: "stock"}}}).
+				Where("id = ?", item.ProductID).
+				Update("stock", gorm.Expr("stock + ?", item.Quantity)).Error; err != nil {
+				return err
+			}
+
+			balance := updated.Stock
+			if item.VariantID != nil {
+				balance = variant.Stock
+			}
+
+			movement.VariantID = item.VariantID
+			if err := recordStockMovement(tx, movement, item.ProductID, item.Quantity, balance); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		s.Status = sale.CanceledStatus
+		s.CanceledAt = &now
+
+		return tx.Model(&s).Updates(map[string]interface{}{
+			"status":      s.Status,
+			"canceled_at": s.CanceledAt,
+		}).Error
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+func saleReference(saleID uint) string {
+	return fmt.Sprintf("sale:%d", saleID)
+}
+
+func roundMoney(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,103 @@
+package gorm
+
+import (
+	"errors"
+	"time"
+
+	"github.com/reinaldo-silva/savina-stock/internal/domain/user"
+	"gorm.io/gorm"
+)
+
+type GormSessionRepository struct {
+	db *gorm.DB
+}
+
+func NewGormSessionRepository(db *gorm.DB) user.SessionRepository {
+	return &GormSessionRepository{db: db}
+}
+
+func (r *GormSessionRepository) Create(session *user.Session, token *user.RefreshToken) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(session).Error; err != nil {
+			return err
+		}
+
+		token.SessionID = session.ID
+		return tx.Omit("Session").Create(token).Error
+	})
+}
+
+func (r *GormSessionRepository) FindRefreshToken(tokenHash string) (*user.RefreshToken, error) {
+	var token user.RefreshToken
+	if err := r.db.Preload("Session").Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, user.ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Rotate spends the used token and stores the next one. Marking the token is
+// conditional, so two concurrent refreshes with the same token cannot both
+// succeed.
+func (r *GormSessionRepository) Rotate(used *user.RefreshToken, next *user.RefreshToken) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		result := tx.Model(&user.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL", used.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return user.ErrRefreshTokenReused
+		}
+
+		if err := tx.Model(&user.Session{}).Where("id = ?", used.SessionID).Update("last_used_at", now).Error; err != nil {
+			return err
+		}
+
+		return tx.Omit("Session").Create(next).Error
+	})
+}
+
+func (r *GormSessionRepository) Revoke(sessionID uint) error {
+	return r.db.Model(&user.Session{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *GormSessionRepository) RevokeAllForUser(userID uint) error {
+	return r.db.Model(&user.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *GormSessionRepository) RevokeOthers(userID uint, keepSessionID uint) error {
+	return r.db.Model(&user.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, keepSessionID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// IsActive also checks the owner of the session, so deactivating a user
+// locks them out even if a revocation was missed.
+func (r *GormSessionRepository) IsActive(sessionID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&user.Session{}).
+		Joins("JOIN users ON users.id = sessions.user_id").
+		Where("sessions.id = ? AND sessions.revoked_at IS NULL AND sessions.expires_at > ? AND users.active = ?", sessionID, time.Now(), true).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// DeleteExpired removes the sessions that expired before the given time;
+// their refresh tokens go with them through the foreign key.
+func (r *GormSessionRepository) DeleteExpired(before time.Time) (int64, error) {
+	result := r.db.Where("expires_at < ?", before).Delete(&user.Session{})
+	return result.RowsAffected, result.Error
+}
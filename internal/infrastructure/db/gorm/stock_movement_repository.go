@@ -0,0 +1,69 @@
+package gorm
+
+import (
+	"context"
+	"time"
+
+	"github.com/reinaldo-silva/savina-stock/internal/domain/stock_movement"
+	"github.com/reinaldo-silva/savina-stock/utils"
+	"gorm.io/gorm"
+)
+
+type GormStockMovementRepository struct {
+	db *gorm.DB
+}
+
+func NewGormStockMovementRepository(db *gorm.DB) stock_movement.StockMovementRepository {
+	return &GormStockMovementRepository{db: db}
+}
+
+func (r *GormStockMovementRepository) GetByProductID(
+	ctx context.Context,
+	productID uint,
+	page int,
+	pageSize int,
+	movementTypes []stock_movement.MovementType,
+	startDate *time.Time,
+	endDate *time.Time) ([]stock_movement.StockMovement, int64, error) {
+	var movements []stock_movement.StockMovement
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&stock_movement.StockMovement{}).Where("product_id = ?", productID)
+
+	if len(movementTypes) > 0 {
+		query = query.Where("type IN ?", movementTypes)
+	}
+
+	if startDate != nil {
+		query = query.Where("created_at >= ?", *startDate)
+	}
+
+	if endDate != nil {
+		query = query.Where("created_at < ?", endDate.AddDate(0, 0, 1))
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.Order("created_at DESC").Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&movements).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return movements, total, nil
+}
+
+// recordStockMovement stores a ledger entry inside the caller's transaction.
+// The acting user comes from the context attached with db.WithContext.
+func recordStockMovement(tx *gorm.DB, movement stock_movement.StockMovement, productID uint, quantity int, balance int) error {
+	movement.ID = 0
+	movement.ProductID = productID
+	movement.Quantity = quantity
+	movement.Balance = balance
+
+	if userID, err := utils.GetCurrentUserID(tx); err == nil {
+		movement.UserID = userID
+	}
+
+	return tx.Create(&movement).Error
+}
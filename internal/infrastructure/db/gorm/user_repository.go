@@ -1,8 +1,14 @@
 package gorm
 
 import (
+	"errors"
+	"strings"
+	"time"
+
 	"github.com/reinaldo-silva/savina-stock/internal/domain/user"
+	"github.com/reinaldo-silva/savina-stock/package/pagination"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type GormUserRepository struct {
@@ -32,15 +38,120 @@ func (r *GormUserRepository) GetAll(
 	return users, total, nil
 }
 
-func (r *GormUserRepository) Create(p user.User) error {
-	return r.db.Create(&p).Error
+func (r *GormUserRepository) GetAllByCursor(
+	cursor *pagination.Cursor,
+	pageSize int) ([]user.User, int64, pagination.Result, error) {
+	var total int64
+
+	k := keyset{Key: "users", IDColumn: "id"}
+	if cursor != nil && cursor.Key != k.Key {
+		return nil, 0, pagination.Result{}, pagination.ErrInvalidCursor
+	}
+
+	if err := r.db.Model(&user.User{}).Count(&total).Error; err != nil {
+		return nil, 0, pagination.Result{}, err
+	}
+
+	users, result, err := findKeysetPage(r.db.Model(&user.User{}), k, cursor, pageSize, func(u user.User) (string, uint) {
+		return "", u.ID
+	})
+	if err != nil {
+		return nil, 0, pagination.Result{}, err
+	}
+
+	return users, total, result, nil
+}
+
+func (r *GormUserRepository) Create(u *user.User) error {
+	err := r.db.Create(u).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return user.ErrEmailInUse
+	}
+	return err
 }
 
+// FindByEmail ignores case, as accounts created before emails were
+// normalized may have uppercase letters.
 func (r *GormUserRepository) FindByEmail(email string) (*user.User, error) {
 	var user user.User
-	result := r.db.Where("email = ?", email).First(&user)
+	result := r.db.Where("LOWER(email) = LOWER(?)", strings.TrimSpace(email)).First(&user)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 	return &user, nil
 }
+
+func (r *GormUserRepository) FindByID(id uint) (*user.User, error) {
+	var u user.User
+	if err := r.db.First(&u, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, user.ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (r *GormUserRepository) UpdatePassword(id uint, passwordHash string) error {
+	return r.db.Model(&user.User{ID: id}).Update("password", passwordHash).Error
+}
+
+func (r *GormUserRepository) MarkEmailVerified(id uint, verifiedAt time.Time) error {
+	return r.db.Model(&user.User{ID: id}).
+		Where("email_verified_at IS NULL").
+		Update("email_verified_at", verifiedAt).Error
+}
+
+// Update saves the profile fields of the user.
+func (r *GormUserRepository) Update(u *user.User) error {
+	err := r.db.Model(u).Select("name", "email", "email_verified_at").Updates(u).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return user.ErrEmailInUse
+	}
+	return err
+}
+
+// UpdateRole returns user.ErrLastAdmin when it would demote the only active
+// admin.
+func (r *GormUserRepository) UpdateRole(id uint, role user.Role) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if role != user.AdminRole {
+			if err := ensureAnotherAdmin(tx, id); err != nil {
+				return err
+			}
+		}
+		return tx.Model(&user.User{ID: id}).Update("role", role).Error
+	})
+}
+
+// SetActive returns user.ErrLastAdmin when it would deactivate the only
+// active admin.
+func (r *GormUserRepository) SetActive(id uint, active bool) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if !active {
+			if err := ensureAnotherAdmin(tx, id); err != nil {
+				return err
+			}
+		}
+		return tx.Model(&user.User{ID: id}).Update("active", active).Error
+	})
+}
+
+// ensureAnotherAdmin locks the active admins until the transaction ends, so
+// two admins demoting each other at the same time cannot both succeed.
+func ensureAnotherAdmin(tx *gorm.DB, id uint) error {
+	var adminIDs []uint
+	if err := tx.Model(&user.User{}).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("role = ? AND active = ?", user.AdminRole, true).
+		Pluck("id", &adminIDs).Error; err != nil {
+		return err
+	}
+
+	for _, adminID := range adminIDs {
+		if adminID == id && len(adminIDs) <= 1 {
+			return user.ErrLastAdmin
+		}
+	}
+	return nil
+}
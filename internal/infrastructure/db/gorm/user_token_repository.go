@@ -0,0 +1,81 @@
+package gorm
+
+import (
+	"time"
+
+	"github.com/reinaldo-silva/savina-stock/internal/domain/user"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GormUserTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewGormUserTokenRepository(db *gorm.DB) user.UserTokenRepository {
+	return &GormUserTokenRepository{db: db}
+}
+
+func (r *GormUserTokenRepository) Create(token *user.UserToken) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND purpose = ? AND used_at IS NULL", token.UserID, token.Purpose).
+			Delete(&user.UserToken{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Omit("User").Create(token).Error
+	})
+}
+
+// Consume spends the token with a conditional update, so the same link
+// cannot be used twice even by concurrent requests.
+func (r *GormUserTokenRepository) Consume(tokenHash string, purpose user.TokenPurpose) (*user.UserToken, error) {
+	return consumeUserToken(r.db, tokenHash, purpose, time.Now())
+}
+
+func (r *GormUserTokenRepository) ResetPassword(tokenHash string, passwordHash string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		token, err := consumeUserToken(tx, tokenHash, user.PasswordResetPurpose, now)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&user.User{ID: token.UserID}).Update("password", passwordHash).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&user.User{ID: token.UserID}).
+			Where("email_verified_at IS NULL").
+			Update("email_verified_at", now).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&user.Session{}).
+			Where("user_id = ? AND revoked_at IS NULL", token.UserID).
+			Update("revoked_at", now).Error
+	})
+}
+
+func consumeUserToken(tx *gorm.DB, tokenHash string, purpose user.TokenPurpose, now time.Time) (*user.UserToken, error) {
+	var token user.UserToken
+
+	result := tx.Model(&token).Clauses(clause.Returning{}).
+		Where("token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", tokenHash, purpose, now).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, user.ErrInvalidUserToken
+	}
+
+	return &token, nil
+}
+
+// DeleteExpired removes the tokens that expired before the given time, used
+// or not.
+func (r *GormUserTokenRepository) DeleteExpired(before time.Time) (int64, error) {
+	result := r.db.Where("expires_at < ?", before).Delete(&user.UserToken{})
+	return result.RowsAffected, result.Error
+}
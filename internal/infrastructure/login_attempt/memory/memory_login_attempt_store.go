@@ -0,0 +1,87 @@
+package memory_login_attempt
+
+import (
+	"sync"
+	"time"
+
+	"github.com/reinaldo-silva/savina-stock/internal/domain/user"
+)
+
+// MemoryLoginAttemptStore keeps the failed attempts in the process. It suits
+// a single instance and tests; with several instances each keeps its own
+// count, so use the Postgres store instead.
+type MemoryLoginAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[string]user.LoginAttempt
+}
+
+func NewMemoryLoginAttemptStore() user.LoginAttemptStore {
+	return &MemoryLoginAttemptStore{attempts: make(map[string]user.LoginAttempt)}
+}
+
+func (s *MemoryLoginAttemptStore) Get(key string) (*user.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt, ok := s.attempts[key]
+	if !ok {
+		return nil, nil
+	}
+	return &attempt, nil
+}
+
+func (s *MemoryLoginAttemptStore) RecordFailure(key string, now time.Time, resetBefore time.Time) (*user.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt, ok := s.attempts[key]
+	if !ok || attempt.LastFailureAt.Before(resetBefore) {
+		attempt = user.LoginAttempt{Key: key}
+	}
+
+	attempt.Failures++
+	attempt.LastFailureAt = now
+	s.attempts[key] = attempt
+
+	return &attempt, nil
+}
+
+func (s *MemoryLoginAttemptStore) Forgive(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt, ok := s.attempts[key]
+	if !ok {
+		return nil
+	}
+
+	attempt.Failures--
+	if attempt.Failures <= 0 {
+		delete(s.attempts, key)
+		return nil
+	}
+	s.attempts[key] = attempt
+	return nil
+}
+
+func (s *MemoryLoginAttemptStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.attempts, key)
+	return nil
+}
+
+func (s *MemoryLoginAttemptStore) DeleteStale(before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	for key, attempt := range s.attempts {
+		if attempt.LastFailureAt.Before(before) {
+			delete(s.attempts, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,45 @@
+package file_mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/reinaldo-silva/savina-stock/internal/infrastructure/mailer"
+)
+
+// FileMailer writes each message to its own file in a directory, which is
+// enough to follow the reset and verification links during development.
+type FileMailer struct {
+	Dir string
+}
+
+func NewFileMailer(dir string) (mailer.Implementation, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("mailer directory is required")
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("could not create mailer directory: %v", err)
+	}
+
+	return &FileMailer{Dir: dir}, nil
+}
+
+func (fm *FileMailer) Send(message mailer.Message) error {
+	var body strings.Builder
+	body.WriteString(fmt.Sprintf("To: %s\n", message.To))
+	body.WriteString(fmt.Sprintf("Subject: %s\n", message.Subject))
+	body.WriteString("\n")
+	body.WriteString(message.Body)
+	body.WriteString("\n")
+
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), strings.NewReplacer("@", "_at_", "/", "_").Replace(message.To))
+	if err := os.WriteFile(filepath.Join(fm.Dir, name), []byte(body.String()), 0o644); err != nil {
+		return fmt.Errorf("could not write email: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,11 @@
+package mailer
+
+type Message struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+type Implementation interface {
+	Send(message Message) error
+}
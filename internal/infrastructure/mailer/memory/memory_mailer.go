@@ -0,0 +1,46 @@
+package memory_mailer
+
+import (
+	"sync"
+
+	"github.com/reinaldo-silva/savina-stock/internal/infrastructure/mailer"
+)
+
+// MemoryMailer keeps the sent messages instead of delivering them, so tests
+// can read the links that would have been emailed.
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []mailer.Message
+}
+
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+func (mm *MemoryMailer) Send(message mailer.Message) error {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	mm.messages = append(mm.messages, message)
+	return nil
+}
+
+func (mm *MemoryMailer) Messages() []mailer.Message {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	return append([]mailer.Message(nil), mm.messages...)
+}
+
+// Last returns the latest message sent to the address.
+func (mm *MemoryMailer) Last(to string) (mailer.Message, bool) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	for i := len(mm.messages) - 1; i >= 0; i-- {
+		if mm.messages[i].To == to {
+			return mm.messages[i], true
+		}
+	}
+	return mailer.Message{}, false
+}
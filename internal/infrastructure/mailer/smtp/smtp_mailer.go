@@ -0,0 +1,53 @@
+package smtp_mailer
+
+import (
+	"fmt"
+	"net/smtp"
+	"strings"
+
+	"github.com/reinaldo-silva/savina-stock/config"
+	"github.com/reinaldo-silva/savina-stock/internal/infrastructure/mailer"
+)
+
+type SMTPMailer struct {
+	Addr string
+	Auth smtp.Auth
+	From string
+}
+
+func NewSMTPMailer(cfg config.SMTPConfig) (mailer.Implementation, error) {
+	if cfg.Host == "" || cfg.From == "" {
+		return nil, fmt.Errorf("smtp host and sender are required")
+	}
+
+	// Local stand-ins such as MailHog accept mail without authentication.
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+
+	return &SMTPMailer{
+		Addr: fmt.Sprintf("%s:%s", cfg.Host, cfg.Port),
+		Auth: auth,
+		From: cfg.From,
+	}, nil
+}
+
+func (sm *SMTPMailer) Send(message mailer.Message) error {
+	var body strings.Builder
+	body.WriteString(fmt.Sprintf("From: %s\r\n", sm.From))
+	body.WriteString(fmt.Sprintf("To: %s\r\n", message.To))
+	body.WriteString(fmt.Sprintf("Subject: %s\r\n", message.Subject))
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=\"UTF-8\"\r\n")
+	body.WriteString("\r\n")
+	body.WriteString(message.Body)
+	body.WriteString("\r\n")
+
+	err := smtp.SendMail(sm.Addr, sm.Auth, sm.From, []string{message.To}, []byte(body.String()))
+	if err != nil {
+		return fmt.Errorf("could not send email: %v", err)
+	}
+
+	return nil
+}
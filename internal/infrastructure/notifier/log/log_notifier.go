@@ -0,0 +1,19 @@
+package log_notifier
+
+import (
+	"log"
+
+	"github.com/reinaldo-silva/savina-stock/internal/infrastructure/notifier"
+	"github.com/reinaldo-silva/savina-stock/utils"
+)
+
+type LogNotifier struct{}
+
+func NewLogNotifier() notifier.Implementation {
+	return &LogNotifier{}
+}
+
+func (ln *LogNotifier) Notify(notification notifier.Notification) error {
+	log.Printf("[%s] %s - %s %s", notification.Event, notification.Subject, notification.Message, utils.ToJSON(notification.Data))
+	return nil
+}
@@ -0,0 +1,12 @@
+package notifier
+
+type Notification struct {
+	Event   string                 `json:"event"`
+	Subject string                 `json:"subject"`
+	Message string                 `json:"message"`
+	Data    map[string]interface{} `json:"data,omitempty"`
+}
+
+type Implementation interface {
+	Notify(notification Notification) error
+}
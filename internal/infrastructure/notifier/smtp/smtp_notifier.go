@@ -0,0 +1,59 @@
+package smtp_notifier
+
+import (
+	"fmt"
+	"net/smtp"
+	"strings"
+
+	"github.com/reinaldo-silva/savina-stock/config"
+	"github.com/reinaldo-silva/savina-stock/internal/infrastructure/notifier"
+)
+
+type SMTPNotifier struct {
+	Addr       string
+	Auth       smtp.Auth
+	From       string
+	Recipients []string
+}
+
+func NewSMTPNotifier(cfg config.SMTPConfig, recipients []string) (notifier.Implementation, error) {
+	if cfg.Host == "" || cfg.From == "" {
+		return nil, fmt.Errorf("smtp host and sender are required")
+	}
+
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("at least one notification recipient is required")
+	}
+
+	// Local stand-ins such as MailHog accept mail without authentication.
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+
+	return &SMTPNotifier{
+		Addr:       fmt.Sprintf("%s:%s", cfg.Host, cfg.Port),
+		Auth:       auth,
+		From:       cfg.From,
+		Recipients: recipients,
+	}, nil
+}
+
+func (sn *SMTPNotifier) Notify(notification notifier.Notification) error {
+	var body strings.Builder
+	body.WriteString(fmt.Sprintf("From: %s\r\n", sn.From))
+	body.WriteString(fmt.Sprintf("To: %s\r\n", strings.Join(sn.Recipients, ", ")))
+	body.WriteString(fmt.Sprintf("Subject: %s\r\n", notification.Subject))
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=\"UTF-8\"\r\n")
+	body.WriteString("\r\n")
+	body.WriteString(notification.Message)
+	body.WriteString("\r\n")
+
+	err := smtp.SendMail(sn.Addr, sn.Auth, sn.From, sn.Recipients, []byte(body.String()))
+	if err != nil {
+		return fmt.Errorf("could not send email: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package webhook_notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/reinaldo-silva/savina-stock/internal/infrastructure/notifier"
+)
+
+type WebhookNotifier struct {
+	Client *http.Client
+	URL    string
+}
+
+func NewWebhookNotifier(url string) (notifier.Implementation, error) {
+	if url == "" {
+		return nil, fmt.Errorf("webhook url is required")
+	}
+
+	return &WebhookNotifier{
+		Client: &http.Client{Timeout: 10 * time.Second},
+		URL:    url,
+	}, nil
+}
+
+func (wn *WebhookNotifier) Notify(notification notifier.Notification) error {
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("could not encode notification: %v", err)
+	}
+
+	resp, err := wn.Client.Post(wn.URL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("could not send webhook: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -12,18 +12,26 @@ import (
 	"github.com/reinaldo-silva/savina-stock/utils"
 )
 
+// SessionValidator tells whether the session an access token was issued for
+// is still active, i.e. not revoked by a sign-out.
+type SessionValidator interface {
+	IsSessionActive(sessionID uint) (bool, error)
+}
+
 type JwtMiddleware struct {
 	secretKey []byte
+	sessions  SessionValidator
 }
 
 type Claims struct {
-	UserID uint   `json:"user_id"`
-	Role   string `json:"role"`
+	UserID    uint   `json:"user_id"`
+	Role      string `json:"role"`
+	SessionID uint   `json:"sid"`
 	jwt.RegisteredClaims
 }
 
-func NewJwtMiddleware(secretKey []byte) *JwtMiddleware {
-	return &JwtMiddleware{secretKey: secretKey}
+func NewJwtMiddleware(secretKey []byte, sessions SessionValidator) *JwtMiddleware {
+	return &JwtMiddleware{secretKey: secretKey, sessions: sessions}
 }
 
 func (m *JwtMiddleware) ValidateToken(next http.Handler) http.Handler {
@@ -63,11 +71,22 @@ func (m *JwtMiddleware) ValidateToken(next http.Handler) http.Handler {
 
 		if claims, ok := token.Claims.(*Claims); ok && token.Valid {
 
+			// Tokens without a session predate revocation and are refused.
+			active, err := m.sessions.IsSessionActive(claims.SessionID)
+			if claims.SessionID == 0 || err != nil || !active {
+				appError := error_response.NewAppError("Session expired or revoked", http.StatusUnauthorized)
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(appError.StatusCode)
+				json.NewEncoder(w).Encode(appError)
+				return
+			}
+
 			userID := claims.UserID
 			role := claims.Role
 
 			ctx := context.WithValue(r.Context(), utils.GetContextKeys().UserIDKey, userID)
 			ctx = context.WithValue(ctx, utils.GetContextKeys().UserRoleKey, role)
+			ctx = context.WithValue(ctx, utils.GetContextKeys().SessionKey, claims.SessionID)
 			r = r.WithContext(ctx)
 		} else {
 			appError := error_response.NewAppError("Could not parse token claims", http.StatusUnauthorized)
@@ -0,0 +1,164 @@
+package barcode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image/color"
+	"image/png"
+	"strings"
+
+	bc "github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/ean"
+)
+
+type Format string
+
+const (
+	SVGFormat Format = "svg"
+	PNGFormat Format = "png"
+)
+
+var (
+	ErrInvalidBarcode = errors.New("barcode must be a valid EAN-13 or UPC-A code")
+	ErrInvalidFormat  = errors.New("barcode image format must be svg or png")
+)
+
+const (
+	defaultModuleWidth = 2
+	defaultHeight      = 80
+	quietZoneModules   = 10
+)
+
+// Validate checks that code is a 13 digit EAN-13 or a 12 digit UPC-A code
+// with a correct check digit.
+func Validate(code string) error {
+	if len(code) != 12 && len(code) != 13 {
+		return ErrInvalidBarcode
+	}
+
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return ErrInvalidBarcode
+		}
+	}
+
+	if checkDigit(code[:len(code)-1]) != code[len(code)-1] {
+		return ErrInvalidBarcode
+	}
+
+	return nil
+}
+
+// checkDigit computes the GS1 mod 10 check digit. Weights alternate 3 and 1
+// starting from the rightmost digit, which works for both EAN-13 and UPC-A.
+func checkDigit(digits string) byte {
+	sum := 0
+	weight := 3
+	for i := len(digits) - 1; i >= 0; i-- {
+		sum += int(digits[i]-'0') * weight
+		weight = 4 - weight
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// Normalize returns the EAN-13 form of a valid UPC-A code, so a code is
+// stored the same way whichever form it was typed in. Anything else is
+// returned unchanged.
+func Normalize(code string) string {
+	if len(code) == 12 && Validate(code) == nil {
+		return "0" + code
+	}
+	return code
+}
+
+// Equivalents returns the forms a scanner may read for code. A UPC-A code is
+// read by EAN readers with a leading zero, and the other way around. Codes
+// that are not valid EAN-13/UPC-A, such as SKUs, only have themselves.
+func Equivalents(code string) []string {
+	codes := []string{code}
+	if Validate(code) != nil {
+		return codes
+	}
+	if len(code) == 12 {
+		codes = append(codes, "0"+code)
+	} else if len(code) == 13 && strings.HasPrefix(code, "0") {
+		codes = append(codes, code[1:])
+	}
+	return codes
+}
+
+// Render draws content as a barcode image. Valid EAN-13/UPC-A codes use the
+// EAN symbology, anything else (such as a SKU) is drawn as Code 128.
+func Render(content string, format Format) ([]byte, string, error) {
+	code, err := encode(content)
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch format {
+	case SVGFormat:
+		return renderSVG(code), "image/svg+xml", nil
+	case PNGFormat:
+		data, err := renderPNG(code)
+		return data, "image/png", err
+	}
+
+	return nil, "", ErrInvalidFormat
+}
+
+func encode(content string) (bc.Barcode, error) {
+	if Validate(content) == nil {
+		if len(content) == 12 {
+			content = "0" + content
+		}
+		return ean.Encode(content)
+	}
+	return code128.Encode(content)
+}
+
+func renderPNG(code bc.Barcode) ([]byte, error) {
+	modules := code.Bounds().Dx()
+	scaled, err := bc.Scale(code, modules*defaultModuleWidth, defaultHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, scaled); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func renderSVG(code bc.Barcode) []byte {
+	modules := code.Bounds().Dx()
+	width := (modules + 2*quietZoneModules) * defaultModuleWidth
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`, width, defaultHeight, width, defaultHeight)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#ffffff"/>`, width, defaultHeight)
+
+	// Consecutive dark modules are merged into a single bar.
+	for x := 0; x < modules; {
+		if !isDark(code, x) {
+			x++
+			continue
+		}
+		start := x
+		for x < modules && isDark(code, x) {
+			x++
+		}
+		fmt.Fprintf(&buf, `<rect x="%d" width="%d" height="%d" fill="#000000"/>`,
+			(start+quietZoneModules)*defaultModuleWidth, (x-start)*defaultModuleWidth, defaultHeight)
+	}
+
+	buf.WriteString(`</svg>`)
+	return buf.Bytes()
+}
+
+func isDark(code bc.Barcode, x int) bool {
+	gray := color.GrayModel.Convert(code.At(x, 0)).(color.Gray)
+	return gray.Y < 128
+}
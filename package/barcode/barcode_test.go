@@ -0,0 +1,90 @@
+package barcode
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		err  error
+	}{
+		{"EAN-13", "4006381333931", nil},
+		{"EAN-13 with a leading zero", "0036000291452", nil},
+		{"UPC-A", "036000291452", nil},
+		{"EAN-13 with a wrong check digit", "4006381333932", ErrInvalidBarcode},
+		{"UPC-A with a wrong check digit", "036000291453", ErrInvalidBarcode},
+		{"letter", "40063813339A1", ErrInvalidBarcode},
+		{"space", "400638133393 ", ErrInvalidBarcode},
+		{"too short", "12345", ErrInvalidBarcode},
+		{"too long", "40063813339310", ErrInvalidBarcode},
+		{"empty", "", ErrInvalidBarcode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(tt.code); !errors.Is(err, tt.err) {
+				t.Fatalf("Validate(%q) = %v, want %v", tt.code, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestCheckDigit(t *testing.T) {
+	tests := []struct {
+		digits string
+		want   byte
+	}{
+		{"400638133393", '1'},
+		{"003600029145", '2'},
+		{"03600029145", '2'},
+		{"000000000000", '0'},
+	}
+
+	for _, tt := range tests {
+		if got := checkDigit(tt.digits); got != tt.want {
+			t.Errorf("checkDigit(%q) = %c, want %c", tt.digits, got, tt.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"036000291452", "0036000291452"},
+		{"0036000291452", "0036000291452"},
+		{"4006381333931", "4006381333931"},
+		{"036000291453", "036000291453"},
+		{"BOLSA-AZUL-M", "BOLSA-AZUL-M"},
+	}
+
+	for _, tt := range tests {
+		if got := Normalize(tt.code); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestEquivalents(t *testing.T) {
+	tests := []struct {
+		code string
+		want []string
+	}{
+		{"036000291452", []string{"036000291452", "0036000291452"}},
+		{"0036000291452", []string{"0036000291452", "036000291452"}},
+		{"4006381333931", []string{"4006381333931"}},
+		{"BOLSA-AZUL-M", []string{"BOLSA-AZUL-M"}},
+		{"036000291453", []string{"036000291453"}},
+	}
+
+	for _, tt := range tests {
+		if got := Equivalents(tt.code); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Equivalents(%q) = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
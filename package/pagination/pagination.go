@@ -0,0 +1,90 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+const DefaultPageSize = 10
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor points at the row a keyset page starts after (or before, when
+// Backward is set). Key identifies the ordering the cursor was built for, so
+// a cursor is rejected when the sort of the request changes.
+type Cursor struct {
+	Key      string `json:"k"`
+	Value    string `json:"v,omitempty"`
+	ID       uint   `json:"i"`
+	Backward bool   `json:"b,omitempty"`
+}
+
+// Params is the pagination requested by the client. Cursor mode is used when
+// the cursor parameter is present, even if empty (first page).
+type Params struct {
+	Page       int
+	PageSize   int
+	CursorMode bool
+	Cursor     *Cursor
+}
+
+// Result carries the cursors of the neighbouring pages, empty when there is
+// no such page.
+type Result struct {
+	NextCursor string
+	PrevCursor string
+}
+
+func Encode(c Cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func Decode(value string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Key == "" || c.ID == 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// FromRequest reads page, pageSize and cursor from the query string. pageSize
+// falls back to DefaultPageSize and is capped at maxPageSize.
+func FromRequest(r *http.Request, maxPageSize int) (Params, error) {
+	query := r.URL.Query()
+
+	params := Params{Page: 1, PageSize: DefaultPageSize}
+
+	if page, err := strconv.Atoi(query.Get("page")); err == nil && page >= 1 {
+		params.Page = page
+	}
+
+	if pageSize, err := strconv.Atoi(query.Get("pageSize")); err == nil && pageSize >= 1 {
+		params.PageSize = pageSize
+	}
+
+	if maxPageSize > 0 && params.PageSize > maxPageSize {
+		params.PageSize = maxPageSize
+	}
+
+	if query.Has("cursor") {
+		params.CursorMode = true
+		if value := query.Get("cursor"); value != "" {
+			cursor, err := Decode(value)
+			if err != nil {
+				return params, err
+			}
+			params.Cursor = cursor
+		}
+	}
+
+	return params, nil
+}
@@ -1,8 +1,9 @@
 package error
 
 type AppError struct {
-	StatusCode int    `json:"statusCode"`
-	Message    string `json:"message"`
+	StatusCode int         `json:"statusCode"`
+	Message    string      `json:"message"`
+	Errors     interface{} `json:"errors,omitempty"`
 }
 
 func NewAppError(message string, statusCode ...int) AppError {
@@ -16,3 +17,9 @@ func NewAppError(message string, statusCode ...int) AppError {
 		Message:    message,
 	}
 }
+
+// WithErrors attaches the field-level errors of a rejected request body.
+func (e AppError) WithErrors(errors interface{}) AppError {
+	e.Errors = errors
+	return e
+}
@@ -5,6 +5,9 @@ type AppResponse struct {
 	Data       interface{} `json:"data,omitempty"`
 	Message    string      `json:"message"`
 	Total      *int64      `json:"total,omitempty"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	PrevCursor string      `json:"prev_cursor,omitempty"`
+	Facets     interface{} `json:"facets,omitempty"`
 }
 
 func NewAppResponse(data interface{}, message string, total *int64, statusCode ...int) AppResponse {
@@ -20,3 +23,16 @@ func NewAppResponse(data interface{}, message string, total *int64, statusCode .
 		Total:      total,
 	}
 }
+
+// WithCursors sets the cursors of the pages around a cursor paginated listing.
+func (r AppResponse) WithCursors(next string, prev string) AppResponse {
+	r.NextCursor = next
+	r.PrevCursor = prev
+	return r
+}
+
+// WithFacets attaches the filter counts computed for a listing.
+func (r AppResponse) WithFacets(facets interface{}) AppResponse {
+	r.Facets = facets
+	return r
+}
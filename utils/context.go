@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"context"
 	"fmt"
 
 	"gorm.io/gorm"
@@ -11,24 +12,45 @@ type contextKey string
 const (
 	userIDKey   contextKey = "userID"
 	userRoleKey contextKey = "userRole"
+	sessionKey  contextKey = "sessionID"
 )
 
 type ContextKeys struct {
 	UserIDKey   string `json:"user_id_key"`
 	UserRoleKey string `json:"user_role_key"`
+	SessionKey  string `json:"session_key"`
 }
 
 func GetContextKeys() ContextKeys {
 	return ContextKeys{
 		UserIDKey:   string(userIDKey),
 		UserRoleKey: string(userRoleKey),
+		SessionKey:  string(sessionKey),
 	}
 }
 
+func GetUserIDFromContext(ctx context.Context) (uint, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+
+	userID, ok := ctx.Value(GetContextKeys().UserIDKey).(uint)
+	return userID, ok
+}
+
+func GetSessionIDFromContext(ctx context.Context) (uint, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+
+	sessionID, ok := ctx.Value(GetContextKeys().SessionKey).(uint)
+	return sessionID, ok
+}
+
 func GetCurrentUserID(tx *gorm.DB) (uint, error) {
 
-	if userID, ok := tx.Statement.Context.Value(GetContextKeys().UserIDKey).(uint); ok {
+	if userID, ok := GetUserIDFromContext(tx.Statement.Context); ok {
 		return userID, nil
 	}
-	return 0, fmt.Errorf("userID not found in context for transaction: %v", tx)
+	return 0, fmt.Errorf("userID not found in transaction context")
 }
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Slugify turns a name like "Pão de Açúcar 500g" into "pao-de-acucar-500g".
+// Accents are folded to their base letters and any run of other characters
+// becomes a single hyphen.
+func Slugify(value string) string {
+	var builder strings.Builder
+	pendingHyphen := false
+
+	for _, r := range norm.NFD.String(strings.ToLower(value)) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			continue
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			if pendingHyphen && builder.Len() > 0 {
+				builder.WriteByte('-')
+			}
+			pendingHyphen = false
+			builder.WriteRune(r)
+		default:
+			pendingHyphen = true
+		}
+	}
+
+	return builder.String()
+}
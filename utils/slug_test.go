@@ -0,0 +1,27 @@
+package utils
+
+import "testing"
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"accents", "Pão de Açúcar 500g", "pao-de-acucar-500g"},
+		{"uppercase accents", "ÉCLAIR À LA CRÈME", "eclair-a-la-creme"},
+		{"separator runs", "Bolsa  --  Couro / Azul", "bolsa-couro-azul"},
+		{"leading and trailing separators", "  --Bolsa!--  ", "bolsa"},
+		{"digits", "Kit 2x1", "kit-2x1"},
+		{"nothing usable", "!!! ---", ""},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Slugify(tt.value); got != tt.want {
+				t.Fatalf("Slugify(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -6,9 +6,17 @@ import (
 )
 
 func GenerateImageURL(host, publicID string) string {
+	return fmt.Sprintf("%s/image/%s", baseURL(host), publicID)
+}
+
+func GenerateCategoryImageURL(host, slug string) string {
+	return fmt.Sprintf("%s/category/%s/image", baseURL(host), slug)
+}
+
+func baseURL(host string) string {
 	scheme := "https"
 	if strings.Contains(host, "localhost") {
 		scheme = "http"
 	}
-	return fmt.Sprintf("%s://%s/image/%s", scheme, host, publicID)
+	return fmt.Sprintf("%s://%s", scheme, host)
 }